@@ -0,0 +1,230 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Delivery is the point of the frame a deferred event is delivered at
+type Delivery int
+
+const (
+	// BeforeUpdate is the start of the next update step, after the window
+	// events and InputState are updated and before any task's Update
+	BeforeUpdate Delivery = iota
+	// AfterUpdate is the end of the next update step, after every task's
+	// Update
+	AfterUpdate
+	// EndOfFrame is after every task has drawn, before killed tasks are
+	// removed
+	EndOfFrame
+	numDeliveries
+)
+
+// Subscription is a handle to a handler subscribed to an EventBus
+type Subscription struct {
+	bus    *EventBus
+	typ    reflect.Type
+	fn     reflect.Value
+	owner  CommandState
+	active bool
+}
+
+// Cancel stops the handler being called, it is safe to call from inside a
+// handler
+func (s *Subscription) Cancel() {
+	if s.active {
+		s.active = false
+		s.bus.cancelled(s.typ)
+	}
+}
+
+// Active reports whether the handler will still be called
+func (s *Subscription) Active() bool { return s.active }
+
+// EventBus lets game objects, triggers and states send each other events
+// without knowing about each other. Events are any value, handlers are
+// functions taking one argument and get the events of that type:
+//
+//	type PlayerDied struct{ At sf.Vector2f }
+//
+//	sub := tm.Bus().Subscribe(func(e PlayerDied) { hud.ShowGameOver() })
+//	tm.Bus().Publish(PlayerDied{g.GetPosition()})
+//
+// A handler taking an interface type gets every event implementing it.
+//
+// Publish calls the handlers straight away. Defer queues the event to be
+// delivered at a given point of the frame instead, and is safe to call from
+// any goroutine, Publish only from the update goroutine. Events deferred
+// while a point's events are being delivered wait for its next turn.
+//
+// Subscriptions made with SubscribeState belong to a game state and are
+// cancelled when it is removed from the stack
+type EventBus struct {
+	subs   map[reflect.Type][]*Subscription
+	ifaces []*Subscription
+	depth  int
+	dirty  map[reflect.Type]bool
+
+	mu     sync.Mutex
+	queued [numDeliveries][]interface{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[reflect.Type][]*Subscription), dirty: make(map[reflect.Type]bool)}
+}
+
+// Subscribe calls fn, a func taking a single argument, with every event of
+// that argument's type. It panics if fn isn't such a func
+func (b *EventBus) Subscribe(fn interface{}) *Subscription {
+	return b.SubscribeState(nil, fn)
+}
+
+// SubscribeState is Subscribe for a game state, the subscription is
+// cancelled when g leaves the state stack
+func (b *EventBus) SubscribeState(g CommandState, fn interface{}) *Subscription {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 || t.IsVariadic() {
+		panic(fmt.Sprintf("grout: event handler must be a func with one argument, got %T", fn))
+	}
+
+	s := &Subscription{b, t.In(0), v, g, true}
+	if s.typ.Kind() == reflect.Interface {
+		b.ifaces = append(b.ifaces, s)
+	} else {
+		b.subs[s.typ] = append(b.subs[s.typ], s)
+	}
+	return s
+}
+
+// Publish delivers e to its handlers now
+func (b *EventBus) Publish(e interface{}) {
+	if e == nil {
+		return
+	}
+	t := reflect.TypeOf(e)
+	v := reflect.ValueOf(e)
+
+	// handlers can cancel and publish, so the cancelled subscriptions are
+	// only dropped once the outermost Publish is done with the lists
+	b.depth++
+	defer b.leave()
+
+	// handlers can subscribe more, those get the next event
+	exact := b.subs[t]
+	n := len(exact)
+	for _, s := range exact[:n:n] {
+		if s.active {
+			s.fn.Call([]reflect.Value{v})
+		}
+	}
+	ifaces := b.ifaces
+	n = len(ifaces)
+	for _, s := range ifaces[:n:n] {
+		if s.active && t.Implements(s.typ) {
+			s.fn.Call([]reflect.Value{v})
+		}
+	}
+}
+
+// cancelled drops the cancelled subscriptions of type t, or remembers to
+// once nothing is ranging over the lists
+func (b *EventBus) cancelled(t reflect.Type) {
+	if b.depth != 0 {
+		b.dirty[t] = true
+		return
+	}
+	b.prune(t)
+}
+
+// leave ends a Publish, the outermost one drops every type's cancelled
+// subscriptions
+func (b *EventBus) leave() {
+	if b.depth--; b.depth != 0 {
+		return
+	}
+	for t := range b.dirty {
+		b.prune(t)
+		delete(b.dirty, t)
+	}
+}
+
+// prune drops the cancelled subscriptions of type t
+func (b *EventBus) prune(t reflect.Type) {
+	if t.Kind() == reflect.Interface {
+		b.ifaces = live(b.ifaces)
+		return
+	}
+	if subs := live(b.subs[t]); len(subs) != 0 {
+		b.subs[t] = subs
+	} else {
+		delete(b.subs, t)
+	}
+}
+
+// live returns the active subscriptions of subs, as a new slice if any
+// were cancelled so a publish still ranging over subs isn't disturbed
+func live(subs []*Subscription) []*Subscription {
+	n := 0
+	for _, s := range subs {
+		if s.active {
+			n++
+		}
+	}
+	if n == len(subs) {
+		return subs
+	}
+	ret := make([]*Subscription, 0, n)
+	for _, s := range subs {
+		if s.active {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// Defer queues e to be delivered at the point of the frame when says
+func (b *EventBus) Defer(e interface{}, when Delivery) {
+	if when < 0 || when >= numDeliveries {
+		panic(fmt.Sprintf("grout: unknown event delivery %d", when))
+	}
+	b.mu.Lock()
+	b.queued[when] = append(b.queued[when], e)
+	b.mu.Unlock()
+}
+
+// deliver publishes the events queued for when
+func (b *EventBus) deliver(when Delivery) {
+	b.mu.Lock()
+	q := b.queued[when]
+	b.queued[when] = nil
+	b.mu.Unlock()
+
+	for _, e := range q {
+		b.Publish(e)
+	}
+}
+
+// cancelState cancels the subscriptions belonging to g
+func (b *EventBus) cancelState(g CommandState) {
+	b.depth++
+	defer b.leave()
+	for _, subs := range b.subs {
+		for _, s := range subs {
+			if s.owner == g {
+				s.Cancel()
+			}
+		}
+	}
+	for _, s := range b.ifaces {
+		if s.owner == g {
+			s.Cancel()
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type busPing struct{}
+type busPong struct{}
+
+func TestPublishAfterCancelInHandler(t *testing.T) {
+	b := NewEventBus()
+	var calls []string
+	var i2 *Subscription
+	b.Subscribe(func(e fmt.Stringer) {})
+	b.Subscribe(func(e interface{}) {
+		calls = append(calls, "i1")
+		if _, ok := e.(busPing); ok {
+			i2.Cancel()
+			b.Publish(busPong{})
+		}
+	})
+	i2 = b.Subscribe(func(e interface{}) { calls = append(calls, "i2") })
+	b.Subscribe(func(e interface{}) { calls = append(calls, "i3") })
+
+	b.Publish(busPing{})
+	want := []string{"i1", "i1", "i3", "i3"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+	if len(b.ifaces) != 3 {
+		t.Errorf("cancelled subscription wasn't dropped, %d left", len(b.ifaces))
+	}
+}
+
+func TestNestedPublishOfSameType(t *testing.T) {
+	b := NewEventBus()
+	var calls []string
+	var s2 *Subscription
+	depth := 0
+	b.Subscribe(func(e busPing) {
+		calls = append(calls, "s1")
+		if depth == 0 {
+			depth++
+			s2.Cancel()
+			b.Publish(busPing{})
+		}
+	})
+	s2 = b.Subscribe(func(e busPing) { calls = append(calls, "s2") })
+	b.Subscribe(func(e busPing) { calls = append(calls, "s3") })
+
+	b.Publish(busPing{})
+	want := []string{"s1", "s1", "s3", "s3"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %v, want %v", calls, want)
+	}
+	if n := len(b.subs[reflect.TypeOf(busPing{})]); n != 2 {
+		t.Errorf("got %d subscriptions, want 2", n)
+	}
+}
+
+type busRare struct{}
+
+func TestCancelOtherTypeInHandler(t *testing.T) {
+	b := NewEventBus()
+	rare := b.Subscribe(func(e busRare) {})
+	iface := b.Subscribe(func(e fmt.Stringer) {})
+	b.Subscribe(func(e busPing) {
+		rare.Cancel()
+		iface.Cancel()
+	})
+
+	b.Publish(busPing{})
+	if _, ok := b.subs[reflect.TypeOf(busRare{})]; ok {
+		t.Error("subscription cancelled while publishing another type wasn't dropped")
+	}
+	if len(b.ifaces) != 0 {
+		t.Errorf("%d interface subscriptions left, want 0", len(b.ifaces))
+	}
+	if len(b.dirty) != 0 {
+		t.Errorf("%d types left to prune", len(b.dirty))
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is where a TaskManager reads the time from. Frame times, and so
+// ElpsTime, the interpolators and the movement components, are all worked
+// out from it
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// RealClock returns a Clock that reads the system time, it is what an
+// engine uses unless it is given another one with WithClock
+func RealClock() Clock { return realClock{} }
+
+// ManualClock is a Clock that only moves when told to. Tests use it to
+// advance the engine a known amount of time per frame:
+//
+//	clk := grout.NewManualClock()
+//	tm := grout.NewEngine(cfg, grout.WithClock(clk), grout.WithRenderTarget(t))
+//	for i := 0; i < 60; i++ {
+//	  clk.Advance(time.Second / 60)
+//	  tm.Step()
+//	}
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock() *ManualClock {
+	return &ManualClock{now: time.Unix(0, 0)}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
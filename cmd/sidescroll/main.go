@@ -17,7 +17,7 @@ type MainMenu struct {
 	font  *sf.Font
 }
 
-func (m *MainMenu) Init(w *sf.RenderWindow) {
+func (m *MainMenu) Init(w eng.RenderTarget) {
 	m.rect, _ = sf.NewRectangleShape()
 	m.rect.SetSize(sf.Vector2f{80, 80})
 	m.rect.SetOutlineThickness(3)
@@ -36,11 +36,11 @@ func (m *MainMenu) Init(w *sf.RenderWindow) {
 }
 
 func (m *MainMenu) OnPause() {}
-func (m *MainMenu) OnResume(w *sf.RenderWindow) {
+func (m *MainMenu) OnResume(w eng.RenderTarget) {
 	w.SetView(w.GetDefaultView())
 }
 
-func (m *MainMenu) Draw(w *sf.RenderWindow) {
+func (m *MainMenu) Draw(w eng.RenderTarget) {
 	w.Clear(sf.ColorRed())
 	w.Draw(m.rect, sf.DefaultRenderStates())
 	w.Draw(m.stuff, sf.DefaultRenderStates())
@@ -48,9 +48,8 @@ func (m *MainMenu) Draw(w *sf.RenderWindow) {
 
 func (m *MainMenu) Update() (eng.GameState, bool) {
 
-	evQ := eng.GetTaskManager().GetEventQueue()
-	for e := evQ.Front(); e != nil; e = e.Next() {
-		switch ev := e.Value.(type) {
+	for _, e := range eng.GetTaskManager().Events() {
+		switch ev := e.Event.(type) {
 		case sf.EventKeyPressed:
 			switch ev.Code {
 			case sf.KeyQ:
@@ -75,9 +74,9 @@ type MapScroll struct {
 }
 
 func (m *MapScroll) OnPause()                    {}
-func (m *MapScroll) OnResume(w *sf.RenderWindow) { w.SetView(m.v) }
+func (m *MapScroll) OnResume(w eng.RenderTarget) { w.SetView(m.v) }
 
-func (m *MapScroll) Init(w *sf.RenderWindow) {
+func (m *MapScroll) Init(w eng.RenderTarget) {
 	m.v = w.GetView()
 	// m.v.SetCenter(sf.Vector2f{float32(m.m.Width*m.m.TileWidth) / 2, float32(m.m.Height*m.m.TileHeight) / 2})
 	// m.v.Move(sf.Vector2f{30, 40})
@@ -85,7 +84,8 @@ func (m *MapScroll) Init(w *sf.RenderWindow) {
 
 	crono := eng.NewSpriteObj()
 	if err := crono.LoadAnimations("crono.anim"); err != nil {
-		log.Fatal(err)
+		eng.GetTaskManager().Shutdown(err)
+		return
 	}
 	crono.SetAnim(eng.STAND_RIGHT)
 	m.g = eng.NewGameObj(crono, &eng.SideScrollInput{}, &eng.SideScrollMove{}, &eng.NullGraphics{})
@@ -100,27 +100,17 @@ func (m *MapScroll) Init(w *sf.RenderWindow) {
 func (m *MapScroll) Update() (eng.GameState, bool) {
 	// m.v.Move(sf.Vector2f{newX, newY})
 
-	evQ := eng.GetTaskManager().GetEventQueue()
-	for e := evQ.Front(); e != nil; e = e.Next() {
-		switch ev := e.Value.(type) {
-		case sf.EventKeyPressed:
-			switch ev.Code {
-			case sf.KeyEscape:
-				return nil, true
-			default:
-				m.g.InComp.Update(m.g, e.Value.(sf.Event))
-			}
-		default:
-			m.g.InComp.Update(m.g, e.Value.(sf.Event))
-		}
+	if eng.GetTaskManager().InputState().JustPressed(eng.ActionPause) {
+		return nil, true
 	}
+	m.g.PollInput()
 
 	m.g.MvComp.Update(m.g, m.m)
 
 	return nil, false
 }
 
-func (m *MapScroll) Draw(w *sf.RenderWindow) {
+func (m *MapScroll) Draw(w eng.RenderTarget) {
 	w.Clear(sf.ColorBlue())
 	pos := w.MapCoordsToPixel(m.g.GetPosition(), m.v)
 	cx := int(w.GetSize().X / 2)
@@ -159,9 +149,11 @@ func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	tm := eng.GetTaskManager()
 	mm := MainMenu{}
-	eng.InitialGameState(&mm)
+	eng.InitialGameState(eng.FromGameState(&mm))
 
-	tm.Execute()
+	if err := tm.Execute(); err != nil {
+		log.Fatal(err)
+	}
 
 	runtime.UnlockOSThread()
 }
@@ -0,0 +1,229 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// Combo is a sequence of actions recognised as a move, like a quarter
+// circle into a punch or a double tap to dash. Timing is counted in update
+// steps, so with the fixed loop mode it doesn't depend on the frame rate
+type Combo struct {
+	Name  string
+	Steps [][]string
+	// MaxGap is the most update steps allowed between one step of the
+	// combo and the next
+	MaxGap int
+}
+
+// NewCombo parses a combo pattern of steps separated by commas, the
+// actions of a step joined with + have to be held together. Every action
+// has to be bound in the input map in, nil means the default engine's. With
+// attack bound in the [input] settings:
+//
+//	fireball, _ := grout.NewCombo(tm.Input(), "fireball", "move_down, move_down+move_right, move_right+attack", 8)
+//	dash, _ := grout.NewCombo(tm.Input(), "dash", "move_right, move_right", 12)
+//
+// The actions are the ones bound, there is no forward for whichever way a
+// game object faces, so a fighter that turns round needs its combos both
+// ways
+func NewCombo(in *InputMap, name, pattern string, maxGap int) (*Combo, error) {
+	if in == nil {
+		in = GetTaskManager().Input()
+	}
+	c := &Combo{Name: name, MaxGap: maxGap}
+	for _, step := range strings.Split(pattern, ",") {
+		var acts []string
+		for _, a := range strings.Split(step, "+") {
+			if a = strings.TrimSpace(a); a == "" {
+				return nil, fmt.Errorf("grout: empty action in combo %q pattern %q", name, pattern)
+			}
+			if !in.HasAction(a) {
+				return nil, fmt.Errorf("grout: combo %q uses action %q which isn't bound", name, a)
+			}
+			acts = append(acts, a)
+		}
+		c.Steps = append(c.Steps, acts)
+	}
+	if maxGap < 1 {
+		return nil, fmt.Errorf("grout: combo %q needs a gap of at least 1 step", name)
+	}
+	return c, nil
+}
+
+// MoveHandler can be implemented by the input component a ComboInput wraps
+// to be told about the moves it recognises
+type MoveHandler interface {
+	OnMove(g *GameObject, move string)
+}
+
+type bufferedInput struct {
+	tick    uint64
+	pressed map[string]bool
+	held    map[string]bool
+}
+
+// ComboInput is an input component which buffers the recent presses of the
+// actions its combos use and recognises the combos among them. It passes
+// events and polls on to the component it wraps, and recognised moves too
+// if that is a MoveHandler.
+//
+// A step matches when all of its actions are held and at least one was
+// just pressed, an action pressed up to Leniency steps earlier counts as
+// held so the buttons of a step don't need to go down on the very same
+// update. Other presses can come between the steps of a combo as long as
+// each step follows the previous one within MaxGap. Longer combos are
+// checked first, and the buffer is cleared once one is recognised so its
+// presses don't also start another.
+//
+// Since it reads the InputState, tests can drive it by pushing events
+type ComboInput struct {
+	Next     InputComponent
+	Leniency int
+
+	combos  []*Combo
+	actions []string
+	buf     []bufferedInput
+	horizon uint64
+}
+
+func NewComboInput(next InputComponent, combos ...*Combo) *ComboInput {
+	c := &ComboInput{Next: next, Leniency: 3}
+	for _, cb := range combos {
+		c.Add(cb)
+	}
+	return c
+}
+
+// Add adds another combo to recognise
+func (c *ComboInput) Add(cb *Combo) {
+	c.combos = append(c.combos, cb)
+	sort.Stable(byLength(c.combos))
+
+	seen := make(map[string]bool)
+	c.actions = c.actions[:0]
+	c.horizon = 0
+	for _, cb := range c.combos {
+		for _, step := range cb.Steps {
+			for _, a := range step {
+				if !seen[a] {
+					seen[a] = true
+					c.actions = append(c.actions, a)
+				}
+			}
+		}
+		if h := uint64(len(cb.Steps) * cb.MaxGap); h > c.horizon {
+			c.horizon = h
+		}
+	}
+}
+
+func (c *ComboInput) Update(g *GameObject, e sf.Event) {
+	if c.Next != nil {
+		c.Next.Update(g, e)
+	}
+}
+
+func (c *ComboInput) Poll(g *GameObject, in *InputState) {
+	if move := c.record(g.TaskManager().Ticks(), in); move != "" {
+		if h, ok := c.Next.(MoveHandler); ok {
+			h.OnMove(g, move)
+		}
+	}
+	if p, ok := c.Next.(InputPoller); ok {
+		p.Poll(g, in)
+	}
+}
+
+// record buffers this update's presses and returns the move they complete
+func (c *ComboInput) record(tick uint64, in *InputState) string {
+	for len(c.buf) > 0 && c.buf[0].tick+c.horizon+uint64(c.Leniency) < tick {
+		c.buf = c.buf[1:]
+	}
+
+	e := bufferedInput{tick, make(map[string]bool), make(map[string]bool)}
+	for _, a := range c.actions {
+		if in.JustPressed(a) {
+			e.pressed[a] = true
+			e.held[a] = true
+		} else if in.IsDown(a) {
+			e.held[a] = true
+		}
+	}
+	if len(e.pressed) == 0 {
+		return ""
+	}
+	c.buf = append(c.buf, e)
+
+	for _, cb := range c.combos {
+		if c.matches(cb) {
+			c.buf = c.buf[:0]
+			return cb.Name
+		}
+	}
+	return ""
+}
+
+// matches reports whether cb's last step is the latest entry in the buffer
+// and its earlier steps can be found before it in time
+func (c *ComboInput) matches(cb *Combo) bool {
+	last := len(c.buf) - 1
+	if !c.stepAt(cb.Steps[len(cb.Steps)-1], last) {
+		return false
+	}
+	i := last
+	for s := len(cb.Steps) - 2; s >= 0; s-- {
+		j := i - 1
+		for ; j >= 0 && c.buf[i].tick-c.buf[j].tick <= uint64(cb.MaxGap); j-- {
+			if c.stepAt(cb.Steps[s], j) {
+				break
+			}
+		}
+		if j < 0 || c.buf[i].tick-c.buf[j].tick > uint64(cb.MaxGap) {
+			return false
+		}
+		i = j
+	}
+	return true
+}
+
+// stepAt reports whether buffer entry i matches step
+func (c *ComboInput) stepAt(step []string, i int) bool {
+	e := c.buf[i]
+	var since uint64
+	if e.tick > uint64(c.Leniency) {
+		since = e.tick - uint64(c.Leniency)
+	}
+	pressed := false
+	for _, a := range step {
+		pressed = pressed || e.pressed[a]
+		if !e.held[a] && !c.pressedSince(a, i, since) {
+			return false
+		}
+	}
+	return pressed
+}
+
+// pressedSince reports whether a was pressed in an entry before i from
+// tick since on
+func (c *ComboInput) pressedSince(a string, i int, since uint64) bool {
+	for j := i - 1; j >= 0 && c.buf[j].tick >= since; j-- {
+		if c.buf[j].pressed[a] {
+			return true
+		}
+	}
+	return false
+}
+
+type byLength []*Combo
+
+func (b byLength) Len() int           { return len(b) }
+func (b byLength) Less(i, j int) bool { return len(b[i].Steps) > len(b[j].Steps) }
+func (b byLength) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
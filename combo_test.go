@@ -0,0 +1,87 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+type moveRecorder struct {
+	NullInputComponent
+	moves []string
+}
+
+func (m *moveRecorder) OnMove(g *GameObject, move string) {
+	m.moves = append(m.moves, move)
+}
+
+func comboEngine() (TaskManager, *ManualClock) {
+	c := DefaultConfig()
+	c.Input.Bind = []string{"attack key:J"}
+	c.Loop.Mode = LoopFixed
+	clk := NewManualClock()
+	return NewEngine(c, WithClock(clk), WithRenderTarget(NewHeadlessTarget(10, 10))), clk
+}
+
+func TestComboMoves(t *testing.T) {
+	tm, clk := comboEngine()
+	fireball, err := NewCombo(tm.Input(), "fireball", "move_down, move_down+move_right, move_right+attack", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dash, err := NewCombo(tm.Input(), "dash", "move_right, move_right", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &moveRecorder{}
+	g := NewGameObj(nil, NewComboInput(rec, dash, fireball), nil, nil)
+	g.SetTaskManager(tm)
+
+	down, right, attack := sf.KeyDown, sf.KeyRight, sf.KeyJ
+	press := func(k sf.KeyCode) sf.Event { return sf.EventKeyPressed{Code: k} }
+	release := func(k sf.KeyCode) sf.Event { return sf.EventKeyReleased{Code: k} }
+	events := map[int][]sf.Event{
+		// fireball
+		2:  {press(down)},
+		5:  {press(right)},
+		7:  {release(down)},
+		10: {press(attack)},
+		11: {release(attack), release(right)},
+		// a double tap dashes
+		30: {press(right)},
+		32: {release(right)},
+		38: {press(right)},
+		39: {release(right)},
+		// too slowly doesn't
+		60: {press(right)},
+		61: {release(right)},
+		80: {press(right)},
+		81: {release(right)},
+	}
+	for i := 0; i < 100; i++ {
+		for _, e := range events[i] {
+			tm.PushEvent(e)
+		}
+		clk.Advance(time.Second / 60)
+		tm.Step()
+		g.PollInput()
+	}
+
+	want := []string{"fireball", "dash"}
+	if !reflect.DeepEqual(rec.moves, want) {
+		t.Errorf("moves %v, want %v", rec.moves, want)
+	}
+}
+
+func TestComboUnboundAction(t *testing.T) {
+	tm, _ := comboEngine()
+	if _, err := NewCombo(tm.Input(), "fireball", "down, down+forward, forward+attack", 8); err == nil {
+		t.Error("no error for actions that aren't bound")
+	}
+}
@@ -14,6 +14,13 @@ type InputComponent interface {
 	Update(*GameObject, sf.Event)
 }
 
+// InputPoller can be implemented by an InputComponent to read the engine's
+// InputState once per update, see GameObject.PollInput. Polling can't miss
+// a release the way handling single events can
+type InputPoller interface {
+	Poll(*GameObject, *InputState)
+}
+
 type NullInputComponent struct{}
 
 func (n *NullInputComponent) Update(*GameObject, sf.Event) {}
@@ -29,57 +36,68 @@ const (
 	JUMP_FORCE = -400
 )
 
+// PlayerInputEuler moves the object at walking speed along the move_x and
+// move_y axes of the engine's InputMap
 func (pi *PlayerInputEuler) Update(g *GameObject, e sf.Event) {
-	switch ev := e.(type) {
-	case sf.EventKeyPressed:
-		switch ev.Code {
-		case sf.KeyLeft:
-			g.Vel.X = -WALK_ACCEL
-		case sf.KeyRight:
-			g.Vel.X = WALK_ACCEL
-		case sf.KeyUp:
-			g.Vel.Y = -WALK_ACCEL
-		case sf.KeyDown:
-			g.Vel.Y = WALK_ACCEL
-		}
-	case sf.EventKeyReleased:
-		switch ev.Code {
-		case sf.KeyLeft, sf.KeyRight:
-			g.prVel = g.Vel
-			g.Vel.X = 0
-		case sf.KeyUp, sf.KeyDown:
-			g.prVel = g.Vel
-			g.Vel.Y = 0
-		}
+	in := g.TaskManager().Input()
+	if v, ok := in.AxisMoved(AxisMoveX, e); ok {
+		pi.move(g, sf.Vector2f{v, g.Vel.Y / WALK_ACCEL})
+	}
+	if v, ok := in.AxisMoved(AxisMoveY, e); ok {
+		pi.move(g, sf.Vector2f{g.Vel.X / WALK_ACCEL, v})
+	}
+}
+
+func (pi *PlayerInputEuler) Poll(g *GameObject, s *InputState) {
+	pi.move(g, sf.Vector2f{s.Axis(AxisMoveX), s.Axis(AxisMoveY)})
+}
+
+func (pi *PlayerInputEuler) move(g *GameObject, dir sf.Vector2f) {
+	vel := dir.TimesScalar(WALK_ACCEL)
+	if vel.X == 0 && g.Vel.X != 0 || vel.Y == 0 && g.Vel.Y != 0 {
+		g.prVel = g.Vel
 	}
+	g.Vel = vel
 }
 
+// SideScrollInput walks along the move_x axis of the engine's InputMap and
+// jumps on the jump action
 type SideScrollInput struct{}
 
 func (s *SideScrollInput) Update(g *GameObject, e sf.Event) {
-	switch ev := e.(type) {
-	case sf.EventKeyPressed:
-		switch ev.Code {
-		case sf.KeyRight:
-			g.Accel.X = WALK_ACCEL
-			g.AniState = WALK_RIGHT
-		case sf.KeyLeft:
-			g.Accel.X = -WALK_ACCEL
-			g.AniState = WALK_LEFT
-		case sf.KeyUp:
-			if g.onGround {
-				g.Vel.Y = JUMP_FORCE
-			}
-		}
-	case sf.EventKeyReleased:
-		switch ev.Code {
-		case sf.KeyRight:
-			g.Accel.X = 0
-			g.AniState = STAND_RIGHT
-		case sf.KeyLeft:
-			g.AniState = STAND_LEFT
-			g.Accel.X = 0
-		}
+	in := g.TaskManager().Input()
+	if v, ok := in.AxisMoved(AxisMoveX, e); ok {
+		s.walk(g, v)
+	}
+	if in.Pressed(ActionJump, e) {
+		s.jump(g)
+	}
+}
+
+func (s *SideScrollInput) Poll(g *GameObject, in *InputState) {
+	s.walk(g, in.Axis(AxisMoveX))
+	if in.JustPressed(ActionJump) {
+		s.jump(g)
+	}
+}
+
+func (s *SideScrollInput) walk(g *GameObject, v float32) {
+	switch {
+	case v > 0:
+		g.AniState = WALK_RIGHT
+	case v < 0:
+		g.AniState = WALK_LEFT
+	case g.Accel.X < 0 || g.AniState == STAND_LEFT:
+		g.AniState = STAND_LEFT
+	default:
+		g.AniState = STAND_RIGHT
+	}
+	g.Accel.X = v * WALK_ACCEL
+}
+
+func (s *SideScrollInput) jump(g *GameObject) {
+	if g.onGround {
+		g.Vel.Y = JUMP_FORCE
 	}
 }
 
@@ -94,10 +112,11 @@ func (n *NullMovementComponent) Update(*GameObject, *Map) {}
 type SideScrollMove struct{}
 
 func (s *SideScrollMove) Update(g *GameObject, m *Map) {
-	delta := float32(GetTaskManager().ElpsTime().Seconds())
+	delta := float32(g.TaskManager().ElpsTime().Seconds())
 	if delta > 0.5 {
 		delta = 0.5
 	}
+	g.SavePrevious()
 	g.prVel = g.Vel
 
 	grav := sf.Vector2f{0, GRAVITY * delta}
@@ -257,7 +276,8 @@ func (s *SideScrollMove) Update(g *GameObject, m *Map) {
 type BaseMovePlayer struct{}
 
 func (s *BaseMovePlayer) Update(g *GameObject, m *Map) {
-	delta := float32(GetTaskManager().ElpsTime().Seconds())
+	delta := float32(g.TaskManager().ElpsTime().Seconds())
+	g.SavePrevious()
 	v := sf.Vector2f{delta * (g.Vel.X + delta*g.Accel.X*0.5), delta * (g.Vel.Y + delta*g.Accel.Y*0.5)}
 	g.Vel = g.Vel.Plus(g.Accel).TimesScalar(delta)
 
@@ -324,7 +344,7 @@ func (n *NullGraphics) Draw(g *GameObject, target sf.RenderTarget, render sf.Ren
 	g.Spr.SetAnim(g.AniState)
 	g.Spr.currAnim.Advance()
 
-	t := g.GetTransform()
+	t := g.RenderTransform()
 	render.Transform.Combine(&t)
 	target.Draw(g.Spr, render)
 }
@@ -351,7 +371,7 @@ func (s *SpriteDraw) Draw(g *GameObject, target sf.RenderTarget, states sf.Rende
 	}
 	g.Spr.currAnim.Advance()
 
-	t := g.GetTransform()
+	t := g.RenderTransform()
 	states.Transform.Combine(&t)
 	target.Draw(g.Spr, states)
 }
@@ -0,0 +1,368 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultConfig returns the settings used for anything the ini file,
+// environment and flags don't set
+func DefaultConfig() Config {
+	var c Config
+	c.Video.W, c.Video.H, c.Video.FPS = 800, 600, 60
+	c.Paths.Res, c.Paths.Spr = "resources", "sprites"
+	c.Loop.Mode = LoopVariable
+	c.Loop.TickRate = defaultTickRate
+	c.Loop.MaxTicks = defaultMaxTicks
+	c.Input.DeadZone = DefaultDeadZone
+	c.copySections()
+	return c
+}
+
+// withDefaults fills the zero sizes and rates in from DefaultConfig, so a
+// Config built by hand only needs the settings it cares about, and gives it
+// the defaults of the registered sections if it has none. The dead zone is
+// left alone as 0 turns it off, and "" paths and loop mode already mean
+// something
+func (c Config) withDefaults() Config {
+	if c.sections == nil {
+		c.copySections()
+	}
+	d := DefaultConfig()
+	if c.Video.W == 0 {
+		c.Video.W = d.Video.W
+	}
+	if c.Video.H == 0 {
+		c.Video.H = d.Video.H
+	}
+	if c.Video.FPS == 0 {
+		c.Video.FPS = d.Video.FPS
+	}
+	if c.Loop.TickRate == 0 {
+		c.Loop.TickRate = d.Loop.TickRate
+	}
+	if c.Loop.MaxTicks == 0 {
+		c.Loop.MaxTicks = d.Loop.MaxTicks
+	}
+	return c
+}
+
+// LoadSettings builds the settings up in layers, each overriding the last:
+//
+//   - DefaultConfig
+//   - the ini file at path, or at $GROUT_CONFIG or the -config flag
+//   - environment variables named GROUT_<SECTION>_<NAME>, like
+//     GROUT_VIDEO_WIDTH=1024, where a list like input.bind is separated by ;
+//   - flags in args named -<section>.<name>, like -loop.mode=fixed, where a
+//     list is given by repeating the flag
+//
+// Any other flags in args are skipped, so the game can parse its own from
+// the same os.Args.
+//
+// The file at path is skipped if it doesn't exist, and a path of "" starts
+// without one, but a file named by $GROUT_CONFIG or -config has to be
+// there. The result is validated, see Config.Validate
+func LoadSettings(path string, args []string) (Config, error) {
+	return loadLayers(path, true, os.Environ(), args)
+}
+
+func loadLayers(path string, optional bool, env []string, args []string) (Config, error) {
+	c := DefaultConfig()
+	fields := configFields(&c)
+
+	// flags are parsed first since they can name the file, but applied last
+	fs := flag.NewFlagSet("grout", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.String("config", "", "settings `file` to load")
+	var set []flagValue
+	for _, f := range fields {
+		fs.Var(&flagValue{f, "", &set}, f.section+"."+f.name, "sets "+f.name+" in the ["+f.section+"] section")
+	}
+	if err := fs.Parse(settingsArgs(fs, args)); err != nil {
+		return c, fmt.Errorf("grout: settings flags: %v", err)
+	}
+
+	vars := make(map[string]string)
+	for _, kv := range env {
+		if i := strings.Index(kv, "="); i > 0 && strings.HasPrefix(kv, "GROUT_") {
+			vars[kv[:i]] = kv[i+1:]
+		}
+	}
+	if p, ok := vars["GROUT_CONFIG"]; ok {
+		path, optional = p, false
+	}
+	if p := fs.Lookup("config").Value.String(); p != "" {
+		path, optional = p, false
+	}
+
+	c.source = settingsSource{path, optional, env, args}
+	if path != "" {
+		if _, err := os.Stat(path); !(optional && os.IsNotExist(err)) {
+			if err := c.readSettingsFile(path); err != nil {
+				return c, fmt.Errorf("grout: reading settings %s: %v", path, err)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		name := "GROUT_" + strings.ToUpper(f.section+"_"+f.name)
+		if v, ok := vars[name]; ok {
+			var err error
+			if f.v.Kind() == reflect.Slice {
+				f.v.Set(reflect.Zero(f.v.Type()))
+				for _, item := range strings.Split(v, ";") {
+					if err = f.set(strings.TrimSpace(item)); err != nil {
+						break
+					}
+				}
+			} else {
+				err = f.set(v)
+			}
+			if err != nil {
+				return c, fmt.Errorf("grout: settings variable %s: %v", name, err)
+			}
+		}
+	}
+
+	cleared := make(map[string]bool)
+	for _, s := range set {
+		if s.f.v.Kind() == reflect.Slice && !cleared[s.f.key()] {
+			cleared[s.f.key()] = true
+			s.f.v.Set(reflect.Zero(s.f.v.Type()))
+		}
+		if err := s.f.set(s.val); err != nil {
+			return c, fmt.Errorf("grout: settings flag -%s: %v", s.f.key(), err)
+		}
+	}
+
+	return c, c.Validate()
+}
+
+// settingsArgs picks the flags fs knows, and their values, out of args so
+// the game can have flags of its own. Everything after -- is left alone
+func settingsArgs(fs *flag.FlagSet, args []string) []string {
+	var ret []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		if len(a) < 2 || a[0] != '-' {
+			continue
+		}
+		name := strings.TrimPrefix(a[1:], "-")
+		hasValue := false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, hasValue = name[:j], true
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		ret = append(ret, a)
+		if b, ok := f.Value.(interface {
+			IsBoolFlag() bool
+		}); hasValue || ok && b.IsBoolFlag() {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			ret = append(ret, args[i])
+		}
+	}
+	return ret
+}
+
+// Validate checks that the settings make sense, returning an error naming
+// every one that doesn't
+func (c *Config) Validate() error {
+	var bad []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			bad = append(bad, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Video.W > 0, "video.width must be above 0")
+	check(c.Video.H > 0, "video.height must be above 0")
+	check(c.Video.FPS > 0 || c.Video.Headless, "video.fps must be above 0")
+	check(c.Loop.Mode == "" || c.Loop.Mode == LoopVariable || c.Loop.Mode == LoopFixed,
+		"loop.mode must be %s or %s, not %q", LoopVariable, LoopFixed, c.Loop.Mode)
+	check(c.Loop.TickRate > 0, "loop.tickrate must be above 0")
+	check(c.Loop.MaxTicks > 0, "loop.maxticks must be above 0")
+	check(c.Debug.Record == "" || c.Debug.Record != c.Debug.Replay, "debug.record and debug.replay can't be the same file")
+	if _, err := NewInputMap(*c); err != nil {
+		bad = append(bad, strings.TrimPrefix(err.Error(), "grout: "))
+	}
+	for _, s := range c.sections {
+		if v, ok := s.v.Addr().Interface().(interface {
+			Validate() error
+		}); ok {
+			if err := v.Validate(); err != nil {
+				bad = append(bad, s.name+": "+err.Error())
+			}
+		}
+	}
+
+	if len(bad) != 0 {
+		return errors.New("grout: bad settings: " + strings.Join(bad, "; "))
+	}
+	return nil
+}
+
+// WriteTo writes the settings out in the ini format LoadConfig reads, so
+// the effective settings can be saved
+func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	write := func(format string, args ...interface{}) {
+		m, _ := fmt.Fprintf(bw, format, args...)
+		n += int64(m)
+	}
+
+	section := ""
+	for _, f := range configFields(c) {
+		if f.section != section {
+			if section != "" {
+				write("\n")
+			}
+			section = f.section
+			write("[%s]\n", section)
+		}
+		if f.v.Kind() == reflect.Slice {
+			for i := 0; i < f.v.Len(); i++ {
+				write("%s = %s\n", f.name, quoteValue(f.v.Index(i).String()))
+			}
+			continue
+		}
+		write("%s = %s\n", f.name, quoteValue(fmt.Sprint(f.v.Interface())))
+	}
+	return n, bw.Flush()
+}
+
+// SaveConfig writes c to the named file, see Config.WriteTo
+func SaveConfig(c Config, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err = c.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func quoteValue(s string) string {
+	if s == strings.TrimSpace(s) && !strings.ContainsAny(s, ";#\"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// configField is one variable of the settings, found through the gcfg tags
+type configField struct {
+	section string
+	name    string
+	v       reflect.Value
+}
+
+func (f configField) key() string { return f.section + "." + f.name }
+
+// configFields lists the variables of every section of c in order, the
+// engine's first and then the game's
+func configFields(c *Config) []configField {
+	var ret []configField
+	cv := reflect.ValueOf(c).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		if cv.Type().Field(i).PkgPath == "" && cv.Field(i).Kind() == reflect.Struct {
+			ret = sectionFields(ret, strings.ToLower(cv.Type().Field(i).Name), cv.Field(i))
+		}
+	}
+	for _, s := range c.sections {
+		ret = sectionFields(ret, s.name, s.v)
+	}
+	return ret
+}
+
+func sectionFields(ret []configField, section string, sec reflect.Value) []configField {
+	st := sec.Type()
+	for j := 0; j < st.NumField(); j++ {
+		if st.Field(j).PkgPath != "" {
+			continue
+		}
+		name := st.Field(j).Tag.Get("gcfg")
+		if name == "" {
+			name = strings.ToLower(st.Field(j).Name)
+		}
+		ret = append(ret, configField{section, name, sec.Field(j)})
+	}
+	return ret
+}
+
+// set parses s into the field, appending it if the field is a list
+func (f configField) set(s string) error {
+	switch f.v.Kind() {
+	case reflect.String:
+		f.v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%s: %q isn't true or false", f.key(), s)
+		}
+		f.v.SetBool(b)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, f.v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: %q isn't a whole number of 0 or more", f.key(), s)
+		}
+		f.v.SetUint(u)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, f.v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: %q isn't a whole number", f.key(), s)
+		}
+		f.v.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(s, f.v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: %q isn't a number", f.key(), s)
+		}
+		f.v.SetFloat(fl)
+	case reflect.Slice:
+		if f.v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%s: unsupported setting type %s", f.key(), f.v.Type())
+		}
+		f.v.Set(reflect.Append(f.v, reflect.ValueOf(s)))
+	default:
+		return fmt.Errorf("%s: unsupported setting type %s", f.key(), f.v.Type())
+	}
+	return nil
+}
+
+// flagValue records a settings flag so it can be applied after the file
+// and environment
+type flagValue struct {
+	f   configField
+	val string
+	set *[]flagValue
+}
+
+func (v *flagValue) String() string { return v.val }
+func (v *flagValue) Set(s string) error {
+	*v.set = append(*v.set, flagValue{v.f, s, nil})
+	return nil
+}
+func (v *flagValue) IsBoolFlag() bool { return v.f.v.Kind() == reflect.Bool }
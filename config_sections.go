@@ -0,0 +1,236 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+
+	"code.google.com/p/gcfg"
+)
+
+type configSection struct {
+	name string
+	v    reflect.Value
+}
+
+var registered []configSection
+
+// RegisterSection adds a section of the game's own to the settings, read
+// from settings.ini along with the engine's sections:
+//
+//	type Audio struct {
+//		Music   float64 `gcfg:"music"`
+//		Effects float64 `gcfg:"effects"`
+//	}
+//
+//	grout.RegisterSection("audio", &Audio{Music: 0.8, Effects: 1})
+//
+// defaults must be a pointer to a struct of strings, bools, numbers and
+// string lists, and its values are used for anything the settings don't
+// set. Each loaded Config gets its own copy, see Config.Section. The
+// section also gets environment variables and flags the same as the
+// engine's, and if it has a Validate() error method that is checked with
+// the rest of the settings.
+//
+// Sections need to be registered before the settings are loaded, usually
+// from an init function. RegisterSection panics if the name is already
+// taken
+func RegisterSection(name string, defaults interface{}) {
+	v := reflect.ValueOf(defaults)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("grout: settings section %s must be a pointer to a struct, got %T", name, defaults))
+	}
+	name = strings.ToLower(name)
+	if name == "" || engineSections()[name] {
+		panic(fmt.Sprintf("grout: settings section %q can't be registered", name))
+	}
+	for _, s := range registered {
+		if s.name == name {
+			panic(fmt.Sprintf("grout: settings section %s is already registered", name))
+		}
+	}
+	registered = append(registered, configSection{name, v.Elem()})
+}
+
+// Section returns the game section registered under name, as a pointer of
+// the same type passed to RegisterSection, or nil if there isn't one:
+//
+//	audio := tm.GetSettings().Section("audio").(*Audio)
+func (c *Config) Section(name string) interface{} {
+	name = strings.ToLower(name)
+	for _, s := range c.sections {
+		if s.name == name {
+			return s.v.Addr().Interface()
+		}
+	}
+	return nil
+}
+
+// UnknownSections lists the sections of the settings file which neither
+// the engine nor the game knows about. They are skipped with a warning
+// rather than failing the load
+func (c *Config) UnknownSections() []string {
+	return c.unknown
+}
+
+// copySections gives c its own copy of each registered section
+func (c *Config) copySections() {
+	c.sections = make([]configSection, len(registered))
+	for i, s := range registered {
+		v := reflect.New(s.v.Type()).Elem()
+		v.Set(s.v)
+		c.sections[i] = configSection{s.name, v}
+	}
+}
+
+func engineSections() map[string]bool {
+	ret := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" && t.Field(i).Type.Kind() == reflect.Struct {
+			ret[strings.ToLower(t.Field(i).Name)] = true
+		}
+	}
+	return ret
+}
+
+// readSettingsFile reads the ini file at path into c. The engine's sections
+// are handed to gcfg, blanking out the other lines so its line numbers stay
+// right, and the game's sections are parsed here in the same pass
+func (c *Config) readSettingsFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]configField)
+	for _, f := range configFields(c) {
+		fields[f.key()] = f
+	}
+	engine := engineSections()
+	listed := make(map[string]bool)
+
+	var out bytes.Buffer
+	section, keep := "", true
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				return fmt.Errorf("line %d: missing ] in section header", n)
+			}
+			header := strings.TrimSpace(line[1:end])
+			section = strings.ToLower(header)
+			if i := strings.IndexAny(section, " \t\""); i >= 0 {
+				section = section[:i]
+			}
+			switch {
+			case engine[section]:
+				keep = true
+			case c.Section(section) != nil:
+				if section != strings.ToLower(header) {
+					return fmt.Errorf("line %d: [%s] can't have subsections", n, section)
+				}
+				keep = false
+			default:
+				keep, section = false, ""
+				if !listed[header] {
+					listed[header] = true
+					c.unknown = append(c.unknown, header)
+					log.Printf("grout: ignoring unknown settings section [%s] in %s", header, path)
+				}
+			}
+		}
+		if keep {
+			out.WriteString(sc.Text())
+		} else if section != "" && line != "" && line[0] != '[' && line[0] != ';' && line[0] != '#' {
+			if err := setLine(fields, section, line); err != nil {
+				return fmt.Errorf("line %d: %v", n, err)
+			}
+		}
+		out.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return gcfg.ReadStringInto(c, out.String())
+}
+
+// setLine sets one name = value line of a game section the way gcfg does:
+// a name on its own means true and a blank value empties a list
+func setLine(fields map[string]configField, section, line string) error {
+	name, value, hasValue := line, "", false
+	if i := strings.Index(line, "="); i >= 0 {
+		name, hasValue = line[:i], true
+		var err error
+		if value, err = unquoteValue(line[i+1:]); err != nil {
+			return err
+		}
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	f, ok := fields[section+"."+name]
+	if !ok {
+		return fmt.Errorf("unknown setting %s in [%s]", name, section)
+	}
+	switch {
+	case !hasValue && f.v.Kind() == reflect.Bool:
+		f.v.SetBool(true)
+		return nil
+	case !hasValue:
+		return fmt.Errorf("%s: missing value", f.key())
+	case value == "" && f.v.Kind() == reflect.Slice:
+		f.v.Set(reflect.Zero(f.v.Type()))
+		return nil
+	}
+	return f.set(value)
+}
+
+// unquoteValue undoes quoteValue, dropping any trailing comment
+func unquoteValue(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	var b []byte
+	quoted, kept := false, 0
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '\\':
+			if i++; i == len(s) {
+				return "", errors.New("unfinished escape at the end of the value")
+			}
+			switch s[i] {
+			case '\\', '"':
+				b = append(b, s[i])
+			case 'n':
+				b = append(b, '\n')
+			case 't':
+				b = append(b, '\t')
+			default:
+				return "", fmt.Errorf("unknown escape \\%c", s[i])
+			}
+			kept = len(b)
+		case ch == '"':
+			quoted = !quoted
+			kept = len(b)
+		case !quoted && (ch == ';' || ch == '#'):
+			i = len(s)
+		default:
+			b = append(b, ch)
+			if quoted {
+				kept = len(b)
+			}
+		}
+	}
+	if quoted {
+		return "", errors.New("missing closing quote")
+	}
+	return string(b[:kept]) + strings.TrimRight(string(b[kept:]), " \t"), nil
+}
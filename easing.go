@@ -0,0 +1,162 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"math"
+)
+
+// Easing maps the progress t of an animation, from 0 to 1, to how far
+// along the value should be. Every easing here starts at exactly 0 and ends
+// at exactly 1, though back, elastic and bounce pass outside that range on
+// the way.
+//
+// The In form starts slowly, the Out form is the In form mirrored so it
+// ends slowly, and InOut runs the In form for the first half and the Out
+// form for the second. The curves are the usual ones from easings.net
+type Easing func(t float32) float32
+
+const (
+	backC1    = 1.70158
+	backC2    = backC1 * 1.525
+	backC3    = backC1 + 1
+	elasticC4 = 2 * math.Pi / 3
+	elasticC5 = 2 * math.Pi / 4.5
+)
+
+// easeOut mirrors the In form of an easing
+func easeOut(in Easing, t float32) float32 {
+	return 1 - in(1-t)
+}
+
+// easeInOut runs the In form of an easing for the first half and its Out
+// form for the second
+func easeInOut(in Easing, t float32) float32 {
+	if t < 0.5 {
+		return in(2*t) / 2
+	}
+	return 1 - in(2-2*t)/2
+}
+
+// Linear doesn't ease at all
+func Linear(t float32) float32 { return t }
+
+func EaseInQuad(t float32) float32    { return t * t }
+func EaseOutQuad(t float32) float32   { return easeOut(EaseInQuad, t) }
+func EaseInOutQuad(t float32) float32 { return easeInOut(EaseInQuad, t) }
+
+func EaseInCubic(t float32) float32    { return t * t * t }
+func EaseOutCubic(t float32) float32   { return easeOut(EaseInCubic, t) }
+func EaseInOutCubic(t float32) float32 { return easeInOut(EaseInCubic, t) }
+
+func EaseInQuart(t float32) float32    { return t * t * t * t }
+func EaseOutQuart(t float32) float32   { return easeOut(EaseInQuart, t) }
+func EaseInOutQuart(t float32) float32 { return easeInOut(EaseInQuart, t) }
+
+func EaseInQuint(t float32) float32    { return t * t * t * t * t }
+func EaseOutQuint(t float32) float32   { return easeOut(EaseInQuint, t) }
+func EaseInOutQuint(t float32) float32 { return easeInOut(EaseInQuint, t) }
+
+func EaseInSine(t float32) float32 {
+	if t >= 1 {
+		return 1
+	}
+	return float32(1 - math.Cos(float64(t)*math.Pi/2))
+}
+func EaseOutSine(t float32) float32   { return easeOut(EaseInSine, t) }
+func EaseInOutSine(t float32) float32 { return easeInOut(EaseInSine, t) }
+
+func EaseInExpo(t float32) float32 {
+	if t <= 0 {
+		return 0
+	}
+	return float32(math.Pow(2, 10*float64(t)-10))
+}
+func EaseOutExpo(t float32) float32   { return easeOut(EaseInExpo, t) }
+func EaseInOutExpo(t float32) float32 { return easeInOut(EaseInExpo, t) }
+
+func EaseInCirc(t float32) float32 {
+	return float32(1 - math.Sqrt(math.Max(0, 1-float64(t)*float64(t))))
+}
+func EaseOutCirc(t float32) float32   { return easeOut(EaseInCirc, t) }
+func EaseInOutCirc(t float32) float32 { return easeInOut(EaseInCirc, t) }
+
+// EaseInBack pulls back below 0 before heading for 1
+func EaseInBack(t float32) float32 {
+	if t >= 1 {
+		return 1
+	}
+	x := float64(t)
+	return float32(backC3*x*x*x - backC1*x*x)
+}
+
+// EaseOutBack overshoots 1 before settling back on it
+func EaseOutBack(t float32) float32 { return easeOut(EaseInBack, t) }
+
+// EaseInOutBack uses a stronger overshoot than the In and Out forms, the
+// same as the usual curve
+func EaseInOutBack(t float32) float32 {
+	if t >= 1 {
+		return 1
+	}
+	x := float64(t)
+	if x < 0.5 {
+		return float32(math.Pow(2*x, 2) * ((backC2+1)*2*x - backC2) / 2)
+	}
+	return float32((math.Pow(2*x-2, 2)*((backC2+1)*(2*x-2)+backC2) + 2) / 2)
+}
+
+// EaseInElastic wobbles around 0 with a growing swing before snapping to 1
+func EaseInElastic(t float32) float32 {
+	if t <= 0 || t >= 1 {
+		return clamp(0, 1, t)
+	}
+	x := float64(t)
+	return float32(-math.Pow(2, 10*x-10) * math.Sin((10*x-10.75)*elasticC4))
+}
+
+// EaseOutElastic overshoots 1 and wobbles around it as it settles
+func EaseOutElastic(t float32) float32 { return easeOut(EaseInElastic, t) }
+
+// EaseInOutElastic wobbles at both ends, with a wider swing than the In and
+// Out forms the same as the usual curve
+func EaseInOutElastic(t float32) float32 {
+	if t <= 0 || t >= 1 {
+		return clamp(0, 1, t)
+	}
+	x := float64(t)
+	if x < 0.5 {
+		return float32(-(math.Pow(2, 20*x-10) * math.Sin((20*x-11.125)*elasticC5)) / 2)
+	}
+	return float32(math.Pow(2, -20*x+10)*math.Sin((20*x-11.125)*elasticC5)/2 + 1)
+}
+
+// EaseOutBounce drops to 1 and bounces off it a few times, each bounce
+// smaller than the last
+func EaseOutBounce(t float32) float32 {
+	const n1, d1 = 7.5625, 2.75
+	x := float64(t)
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	case x < 1/d1:
+		return float32(n1 * x * x)
+	case x < 2/d1:
+		x -= 1.5 / d1
+		return float32(n1*x*x + 0.75)
+	case x < 2.5/d1:
+		x -= 2.25 / d1
+		return float32(n1*x*x + 0.9375)
+	default:
+		x -= 2.625 / d1
+		return float32(n1*x*x + 0.984375)
+	}
+}
+
+// EaseInBounce is EaseOutBounce mirrored, the bounces grow towards 1
+func EaseInBounce(t float32) float32    { return easeOut(EaseOutBounce, t) }
+func EaseInOutBounce(t float32) float32 { return easeInOut(EaseInBounce, t) }
@@ -0,0 +1,128 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"testing"
+)
+
+var easings = []struct {
+	name  string
+	ease  Easing
+	inOut bool
+}{
+	{"Linear", Linear, true},
+	{"EaseInQuad", EaseInQuad, false},
+	{"EaseOutQuad", EaseOutQuad, false},
+	{"EaseInOutQuad", EaseInOutQuad, true},
+	{"EaseInCubic", EaseInCubic, false},
+	{"EaseOutCubic", EaseOutCubic, false},
+	{"EaseInOutCubic", EaseInOutCubic, true},
+	{"EaseInQuart", EaseInQuart, false},
+	{"EaseOutQuart", EaseOutQuart, false},
+	{"EaseInOutQuart", EaseInOutQuart, true},
+	{"EaseInQuint", EaseInQuint, false},
+	{"EaseOutQuint", EaseOutQuint, false},
+	{"EaseInOutQuint", EaseInOutQuint, true},
+	{"EaseInSine", EaseInSine, false},
+	{"EaseOutSine", EaseOutSine, false},
+	{"EaseInOutSine", EaseInOutSine, true},
+	{"EaseInExpo", EaseInExpo, false},
+	{"EaseOutExpo", EaseOutExpo, false},
+	{"EaseInOutExpo", EaseInOutExpo, true},
+	{"EaseInCirc", EaseInCirc, false},
+	{"EaseOutCirc", EaseOutCirc, false},
+	{"EaseInOutCirc", EaseInOutCirc, true},
+	{"EaseInBack", EaseInBack, false},
+	{"EaseOutBack", EaseOutBack, false},
+	{"EaseInOutBack", EaseInOutBack, true},
+	{"EaseInElastic", EaseInElastic, false},
+	{"EaseOutElastic", EaseOutElastic, false},
+	{"EaseInOutElastic", EaseInOutElastic, true},
+	{"EaseInBounce", EaseInBounce, false},
+	{"EaseOutBounce", EaseOutBounce, false},
+	{"EaseInOutBounce", EaseInOutBounce, true},
+}
+
+func TestEasingEnds(t *testing.T) {
+	if len(easings) != 31 {
+		t.Fatalf("testing %d easings, want 31", len(easings))
+	}
+	for _, e := range easings {
+		if v := e.ease(0); v != 0 {
+			t.Errorf("%s(0) = %v, want 0", e.name, v)
+		}
+		if v := e.ease(1); v != 1 {
+			t.Errorf("%s(1) = %v, want 1", e.name, v)
+		}
+		if !e.inOut {
+			continue
+		}
+		if v := e.ease(0.5); abs32(v-0.5) > 1e-6 {
+			t.Errorf("%s(0.5) = %v, want 0.5", e.name, v)
+		}
+	}
+}
+
+// the easings whose formula is off at an end and is guarded
+func TestEasingGuardedEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		ease Easing
+		t    float32
+		want float32
+	}{
+		{"EaseInSine", EaseInSine, 1, 1},
+		{"EaseInExpo", EaseInExpo, 0, 0},
+		{"EaseInBack", EaseInBack, 1, 1},
+		{"EaseInOutBack", EaseInOutBack, 1, 1},
+		{"EaseInElastic", EaseInElastic, 0, 0},
+		{"EaseInElastic", EaseInElastic, 1, 1},
+		{"EaseInOutElastic", EaseInOutElastic, 0, 0},
+		{"EaseInOutElastic", EaseInOutElastic, 1, 1},
+		{"EaseOutBounce", EaseOutBounce, 0, 0},
+		{"EaseOutBounce", EaseOutBounce, 1, 1},
+	}
+	for _, tt := range tests {
+		if v := tt.ease(tt.t); v != tt.want {
+			t.Errorf("%s(%v) = %v, want %v", tt.name, tt.t, v, tt.want)
+		}
+	}
+}
+
+func TestEasedTimeInterpolatorEnds(t *testing.T) {
+	for _, e := range easings {
+		ei := NewEasedTimeInterpolator(500, 10, 90, e.ease)
+		for i := 0; i < 7; i++ {
+			ei.Update(100)
+		}
+		if v := ei.GetValue(); v != 90 {
+			t.Errorf("%s: ended on %v, want 90", e.name, v)
+		}
+		if ei.IsAlive() {
+			t.Errorf("%s: still alive after its timespan", e.name)
+		}
+	}
+}
+
+func TestEasedTimeInterpolatorNoTime(t *testing.T) {
+	ei := NewEasedTimeInterpolator(0, 10, 90, nil)
+	if v := ei.GetValue(); v != 10 {
+		t.Errorf("starts on %v, want 10", v)
+	}
+	ei.Update(16)
+	if v := ei.GetValue(); v != 90 {
+		t.Errorf("ended on %v, want 90", v)
+	}
+	if ei.IsAlive() {
+		t.Error("still alive")
+	}
+
+	ei = NewEasedTimeInterpolator(0, 10, 90, EaseOutBounce)
+	ei.Update(0)
+	if v := ei.GetValue(); v != 90 {
+		t.Errorf("ended on %v after no time, want 90", v)
+	}
+}
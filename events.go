@@ -0,0 +1,73 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// Event is a window event delivered during one update step. Consuming it
+// stops it from reaching the game states beneath the one that consumed it
+type Event struct {
+	sf.Event
+	consumed bool
+}
+
+func (e *Event) Consume()       { e.consumed = true }
+func (e *Event) Consumed() bool { return e.consumed }
+
+// Events are the events of one update step, in the order they arrived
+type Events []*Event
+
+// Unconsumed returns the events nobody has consumed yet
+func (evs Events) Unconsumed() Events {
+	ret := make(Events, 0, len(evs))
+	for _, e := range evs {
+		if !e.consumed {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// PushEvent queues e to be delivered at the start of the next update step.
+// The window's events are fed in through it, and it is safe to call from
+// any goroutine
+func (tm *taskMgr) PushEvent(e sf.Event) {
+	tm.queMutex.Lock()
+	tm.pending = append(tm.pending, e)
+	tm.queMutex.Unlock()
+}
+
+// takeEvents moves the queued events over to be the current step's. When
+// replaying, the queued ones are dropped for the replay's
+func (tm *taskMgr) takeEvents() {
+	tm.queMutex.Lock()
+	pending := tm.pending
+	tm.pending = nil
+	tm.queMutex.Unlock()
+
+	if tm.replay != nil {
+		pending = tm.replay.take(tm.tick)
+	}
+	if tm.rec != nil {
+		tm.rec.record(tm.tick, pending)
+	}
+
+	tm.events = make(Events, len(pending))
+	for i, e := range pending {
+		tm.events[i] = &Event{Event: e}
+	}
+}
+
+// Events returns the events of the current update step. While a game state
+// is being updated it only returns the events that the states above it
+// left unconsumed, everywhere else it returns them all
+func (tm *taskMgr) Events() Events {
+	if tm.inState {
+		return tm.stateEvents
+	}
+	return tm.events
+}
@@ -21,10 +21,76 @@ type GameObject struct {
 	GrComp GraphicsComponent
 
 	onGround bool
+	prevPos  sf.Vector2f
+	hasPrev  bool
+	tm       TaskManager
 }
 
 func NewGameObj(sp *SpriteObj, ic InputComponent, mv MovementComponent, gr GraphicsComponent) *GameObject {
-	return &GameObject{sf.NewTransformable(), sf.Vector2f{}, sf.Vector2f{}, sf.Vector2f{}, sp, STAND_RIGHT, ic, mv, gr, false}
+	return &GameObject{sf.NewTransformable(), sf.Vector2f{}, sf.Vector2f{}, sf.Vector2f{}, sp, STAND_RIGHT, ic, mv, gr, false, sf.Vector2f{}, false, nil}
+}
+
+// SetTaskManager binds the object, and its sprite, to an engine other than
+// the default one
+func (g *GameObject) SetTaskManager(tm TaskManager) {
+	g.tm = tm
+	if g.Spr != nil {
+		g.Spr.SetTaskManager(tm)
+	}
+}
+
+// TaskManager returns the engine the object is bound to, or the default one
+func (g *GameObject) TaskManager() TaskManager {
+	return orDefault(g.tm)
+}
+
+// PollInput hands the engine's InputState to the input component if it is
+// an InputPoller, states call it once per update
+func (g *GameObject) PollInput() {
+	if p, ok := g.InComp.(InputPoller); ok {
+		p.Poll(g, g.TaskManager().InputState())
+	}
+}
+
+// SavePrevious remembers the current position as the one from the previous
+// update. Movement components call it before moving the object so that
+// RenderTransform can blend between the two
+func (g *GameObject) SavePrevious() {
+	g.prevPos = g.GetPosition()
+	g.hasPrev = true
+}
+
+// RenderTransform returns the object's transform with its position blended
+// between the previous and the current update by the task manager's Alpha
+func (g *GameObject) RenderTransform() sf.Transform {
+	t := g.GetTransform()
+	if !g.hasPrev {
+		return t
+	}
+
+	a := g.TaskManager().Alpha()
+	cur := g.GetPosition()
+	off := g.prevPos.Minus(cur).TimesScalar(1 - a)
+
+	r := sf.TransformIdentity()
+	r.Translate(off.X, off.Y)
+	r.Combine(&t)
+	return r
+}
+
+// GetColor returns the colour of the object's sprite, white if it has none
+func (g *GameObject) GetColor() sf.Color {
+	if g.Spr == nil {
+		return sf.ColorWhite()
+	}
+	return g.Spr.GetColor()
+}
+
+// SetColor tints the object's sprite, see SpriteObj.SetColor
+func (g *GameObject) SetColor(c sf.Color) {
+	if g.Spr != nil {
+		g.Spr.SetColor(c)
+	}
 }
 
 func (g *GameObject) Draw(target sf.RenderTarget, renderStates sf.RenderStates) {
@@ -10,58 +10,310 @@ import (
 
 type gameStateTask struct {
 	BasicTask
-	stk stack
+	stk     stack
+	initial CommandState
+	tm      *taskMgr
+	trans   *runningTransition
+	hold    bool
+	held    []CommandState
+	scheds  map[CommandState]*Scheduler
 }
 
-func (gs *gameStateTask) Start() bool {
-	gs.stk = make(stack, 1)
-	return true
+// runningTransition is a transition being played, exited are the states
+// popped by the change which it still draws, they're let go once it ends
+type runningTransition struct {
+	t        Transition
+	from, to []CommandState
+	exited   []CommandState
+	progress *LinearTimeInterpolator
+}
+
+func (gs *gameStateTask) Start() error {
+	gs.stk = make(stack, 0)
+	return nil
+}
+
+func (gs *gameStateTask) Stop() error {
+	gs.clear()
+	gs.endTransition()
+	gs.tm.KillAllTasks()
+	return nil
+}
+
+func (gs *gameStateTask) top() CommandState {
+	if len(gs.stk) == 0 {
+		return nil
+	}
+	return gs.stk.Top().(CommandState)
+}
+
+// overlay returns the Overlay the i'th state on the stack implements
+func (gs *gameStateTask) overlay(i int) (Overlay, bool) {
+	o, ok := stateValue(gs.stk[i].(CommandState)).(Overlay)
+	return o, ok
+}
+
+// visible returns the states which can be seen, from the deepest one up to
+// the top of the stack
+func (gs *gameStateTask) visible() []CommandState {
+	i := len(gs.stk) - 1
+	for i > 0 {
+		if o, ok := gs.overlay(i); !ok || !o.Transparent() {
+			break
+		}
+		i--
+	}
+	ret := make([]CommandState, 0, len(gs.stk))
+	for ; i >= 0 && i < len(gs.stk); i++ {
+		ret = append(ret, gs.stk[i].(CommandState))
+	}
+	return ret
+}
+
+// updateStates updates the top state and those beneath it that it lets
+// keep running, from the top down. Each state only sees the events that
+// the ones above it left unconsumed. What the states beneath return is
+// ignored, only the top state changes the stack
+func (gs *gameStateTask) updateStates() StateCommand {
+	states := []CommandState{gs.top()}
+	for i := len(gs.stk) - 1; i > 0; i-- {
+		if o, ok := gs.overlay(i); !ok || !o.UpdatesBelow() {
+			break
+		}
+		states = append(states, gs.stk[i-1].(CommandState))
+	}
+
+	var cmd StateCommand
+	for i, g := range states {
+		gs.tm.stateEvents, gs.tm.inState = gs.tm.events.Unconsumed(), true
+		c := g.Update()
+		gs.tm.inState = false
+		if i == 0 {
+			cmd = c
+		}
+	}
+	return cmd
+}
+
+func (gs *gameStateTask) push(s CommandState) {
+	gs.stk.Push(s)
+	s.Init(gs.tm.getTarget())
+}
+
+// pop takes the top state off the stack and lets it go, unless a
+// transition is about to draw it
+func (gs *gameStateTask) pop() {
+	g := gs.stk.Pop().(CommandState)
+	if gs.hold {
+		if s, ok := gs.scheds[g]; ok {
+			s.Pause()
+		}
+		gs.held = append(gs.held, g)
+		return
+	}
+	gs.exit(g)
+}
+
+// exit tells g it has left the stack and cancels its subscriptions and
+// timers
+func (gs *gameStateTask) exit(g CommandState) {
+	if e, ok := stateValue(g).(Exiter); ok {
+		e.OnExit()
+	}
+	gs.tm.bus.cancelState(g)
+	if s, ok := gs.scheds[g]; ok {
+		s.CancelAll()
+		s.Kill()
+		delete(gs.scheds, g)
+	}
+}
+
+// scheduler returns the scheduler belonging to g, making it if need be
+func (gs *gameStateTask) scheduler(g CommandState) *Scheduler {
+	if s, ok := gs.scheds[g]; ok {
+		return s
+	}
+	if gs.scheds == nil {
+		gs.scheds = make(map[CommandState]*Scheduler)
+	}
+	s := NewScheduler(gs.tm)
+	gs.tm.RegisterTrigger(s)
+	gs.scheds[g] = s
+	return s
+}
+
+func (gs *gameStateTask) pause() {
+	g := gs.top()
+	if s, ok := gs.scheds[g]; ok {
+		s.Pause()
+	}
+	g.OnPause()
 }
 
-func (gs *gameStateTask) Stop() {
+func (gs *gameStateTask) clear() {
 	for len(gs.stk) != 0 {
-		gs.stk.Pop()
+		gs.pop()
 	}
-	GetTaskManager().KillAllTasks()
 }
 
-func (gs *gameStateTask) Update() {
+func (gs *gameStateTask) resume() {
 	if len(gs.stk) == 0 {
 		return
 	}
-	state, pop := gs.stk.Top().(GameState).Update()
-	if pop {
-		gs.stk.Pop()
-		if state == nil && len(gs.stk) != 0 {
-			gs.stk.Top().(GameState).OnResume(GetTaskManager().getWindow())
+	g := gs.top()
+	if s, ok := gs.scheds[g]; ok {
+		s.Resume()
+	}
+	g.OnResume(gs.tm.getTarget())
+}
+
+// apply carries out cmd on the stack, reporting whether it changed
+func (gs *gameStateTask) apply(cmd StateCommand) bool {
+	gs.hold = cmd.trans != nil
+	defer func() { gs.hold = false }()
+
+	switch cmd.op {
+	case opPush:
+		if cmd.state == nil {
+			return false
+		}
+		gs.pause()
+		gs.push(cmd.state)
+	case opReplace:
+		if cmd.state == nil {
+			return false
+		}
+		gs.pop()
+		gs.push(cmd.state)
+	case opPop, opPopN:
+		if cmd.n < 1 {
+			return false
+		}
+		for n := cmd.n; n > 0 && len(gs.stk) != 0; n-- {
+			gs.pop()
+		}
+		gs.resume()
+	case opPopTo:
+		if len(gs.stk) == 1 || cmd.pred != nil && cmd.pred(gs.top()) {
+			return false
+		}
+		for len(gs.stk) > 1 && (cmd.pred == nil || !cmd.pred(gs.top())) {
+			gs.pop()
+		}
+		gs.resume()
+	case opClearAndPush:
+		if cmd.state == nil {
+			return false
 		}
+		gs.clear()
+		gs.push(cmd.state)
+	case opQuit:
+		gs.clear()
+	default:
+		return false
 	}
-	if state != nil {
-		if len(gs.stk) != 0 {
-			gs.stk.Top().(GameState).OnPause()
+	return true
+}
+
+func (gs *gameStateTask) Update() {
+	if gs.initial != nil {
+		gs.push(gs.initial)
+		gs.initial = nil
+	}
+
+	if gs.trans != nil {
+		// neither state is updated, or gets input, while the transition runs
+		if gs.trans.progress.IsAlive() {
+			return
+		}
+		gs.endTransition()
+		if len(gs.stk) == 0 {
+			gs.SetCanKill(true)
+			return
 		}
-		gs.stk.Push(state)
-		gs.stk.Top().(GameState).Init(GetTaskManager().getWindow())
 	}
+
 	if len(gs.stk) == 0 {
+		return
+	}
+
+	from := gs.visible()
+	cmd := gs.updateStates()
+	if !gs.apply(cmd) {
+		return
+	}
+
+	if cmd.trans != nil {
+		gs.startTransition(cmd.trans, from, gs.visible())
+	} else if len(gs.stk) == 0 {
 		gs.SetCanKill(true)
 	}
 }
 
-func (gs *gameStateTask) Draw(w *sf.RenderWindow) {
-	if len(gs.stk) == 0 {
+func (gs *gameStateTask) startTransition(t Transition, from, to []CommandState) {
+	p := NewLinearTimeInterpolator(t.Duration(), 0, 1)
+	gs.tm.RegisterInterpolator(p)
+	gs.trans = &runningTransition{t, from, to, gs.held, p}
+	gs.held = nil
+}
+
+// endTransition lets go of the states the transition was drawing which
+// have left the stack
+func (gs *gameStateTask) endTransition() {
+	if gs.trans == nil {
+		return
+	}
+	for _, g := range gs.trans.exited {
+		gs.exit(g)
+	}
+	gs.trans = nil
+}
+
+func drawStates(states []CommandState) func(RenderTarget) {
+	return func(w RenderTarget) {
+		if len(states) == 0 {
+			w.Clear(sf.ColorBlack())
+			return
+		}
+		for _, g := range states {
+			g.Draw(w)
+		}
+	}
+}
+
+func (gs *gameStateTask) Draw(w RenderTarget) {
+	if gs.trans != nil {
+		gs.trans.t.Draw(w, drawStates(gs.trans.from), drawStates(gs.trans.to), gs.trans.progress.GetValue())
 		return
 	}
-	gs.stk.Top().(GameState).Draw(w)
+	for _, g := range gs.visible() {
+		g.Draw(w)
+	}
 }
 
-// Interface to define individual game state behavior
+// Interface to define individual game state behavior, with the original
+// Update returning a state to push and whether to pop this one first. New
+// states implement CommandState instead, a GameState still works through
+// FromGameState
 type GameState interface {
-	Init(*sf.RenderWindow)
+	Init(RenderTarget)
 	Update() (GameState, bool)
-	Draw(*sf.RenderWindow)
+	Draw(RenderTarget)
 	OnPause()
-	OnResume(*sf.RenderWindow)
+	OnResume(RenderTarget)
+}
+
+// Overlay can be implemented by a state that sits on top of another,
+// like a pause menu or a dialogue box.
+//
+// If Transparent returns true the states beneath it are drawn first, down
+// to the nearest one which isn't transparent. If UpdatesBelow returns true
+// the state beneath it keeps being updated, and so on down the stack while
+// those states return true as well. Only the top state gets to push or pop
+// states
+type Overlay interface {
+	Transparent() bool
+	UpdatesBelow() bool
 }
 
 type stack []interface{}
@@ -0,0 +1,132 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"math"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// Standard gamepad buttons, numbered the way SFML reports an XInput style
+// pad. They can be bound by name as well, as in joybutton:a
+const (
+	PadA uint = iota
+	PadB
+	PadX
+	PadY
+	PadLB
+	PadRB
+	PadBack
+	PadStart
+	PadLStick
+	PadRStick
+)
+
+// Standard gamepad axes the way SFML reports an XInput style pad. They can
+// be bound by name as well, as in joyaxis:leftx
+const (
+	PadLeftX    = sf.JoystickX
+	PadLeftY    = sf.JoystickY
+	PadTriggers = sf.JoystickZ
+	PadRightX   = sf.JoystickU
+	PadRightY   = sf.JoystickR
+	PadDPadX    = sf.JoystickPovX
+	PadDPadY    = sf.JoystickPovY
+)
+
+// DefaultDeadZone is used when the [input] settings don't set deadzone
+const DefaultDeadZone = 0.15
+
+var padButtonNames = map[string]uint{
+	"a": PadA, "b": PadB, "x": PadX, "y": PadY, "lb": PadLB, "rb": PadRB,
+	"back": PadBack, "start": PadStart, "lstick": PadLStick, "rstick": PadRStick,
+}
+
+var padAxisNames = map[string]sf.JoystickAxis{
+	"leftx": PadLeftX, "lefty": PadLeftY, "triggers": PadTriggers,
+	"rightx": PadRightX, "righty": PadRightY, "dpadx": PadDPadX, "dpady": PadDPadY,
+}
+
+// deadZone maps v so everything within dz of the centre is 0 and the rest
+// of the range is stretched back out to reach 1
+func deadZone(v, dz float32) float32 {
+	a := abs(v)
+	if a <= dz {
+		return 0
+	}
+	a = clamp(0, 1, (a-dz)/(1-dz))
+	if v < 0 {
+		return -a
+	}
+	return a
+}
+
+// Connected reports whether joystick joy is connected, going by the
+// connect and disconnect events seen so far
+func (s *InputState) Connected(joy uint) bool { return s.joys[joy] }
+
+// Joysticks returns the ids of the connected joysticks in order
+func (s *InputState) Joysticks() []uint {
+	ret := make([]uint, 0, len(s.joys))
+	for j := uint(0); j < sf.JoystickCount; j++ {
+		if s.joys[j] {
+			ret = append(ret, j)
+		}
+	}
+	return ret
+}
+
+// Stick returns the position of a joystick's stick made of the axes x and
+// y. The dead zone is applied to the stick as a whole rather than to each
+// axis, so diagonals aren't snapped to the axes, and the length of the
+// result is at most 1
+func (s *InputState) Stick(joy uint, x, y sf.JoystickAxis) sf.Vector2f {
+	v := sf.Vector2f{s.axes[joyAxisKey{joy, x}], s.axes[joyAxisKey{joy, y}]}
+	l := float32(math.Hypot(float64(v.X), float64(v.Y)))
+	if l == 0 {
+		return v
+	}
+	return v.TimesScalar(deadZone(l, s.tm.Input().DeadZone()) / l)
+}
+
+// PadSideScrollInput is SideScrollInput for one gamepad, walking as fast
+// as the left stick or d-pad is pushed and jumping on A
+type PadSideScrollInput struct {
+	Joy uint
+}
+
+// Update does nothing, the gamepad is read in Poll
+func (p *PadSideScrollInput) Update(*GameObject, sf.Event) {}
+
+func (p *PadSideScrollInput) Poll(g *GameObject, in *InputState) {
+	x := in.Stick(p.Joy, PadLeftX, PadLeftY).X
+	if d := in.JoyAxis(p.Joy, PadDPadX); d != 0 {
+		x = d
+	}
+	var s SideScrollInput
+	s.walk(g, x)
+	if in.JoyButtonJustPressed(p.Joy, PadA) {
+		s.jump(g)
+	}
+}
+
+// PadTopDownInput is PlayerInputEuler for one gamepad, moving in whichever
+// direction the left stick or d-pad points as fast as it is pushed
+type PadTopDownInput struct {
+	Joy uint
+}
+
+// Update does nothing, the gamepad is read in Poll
+func (p *PadTopDownInput) Update(*GameObject, sf.Event) {}
+
+func (p *PadTopDownInput) Poll(g *GameObject, in *InputState) {
+	dir := in.Stick(p.Joy, PadLeftX, PadLeftY)
+	if d := (sf.Vector2f{in.JoyAxis(p.Joy, PadDPadX), in.JoyAxis(p.Joy, PadDPadY)}); d.X != 0 || d.Y != 0 {
+		dir = d
+	}
+	var pi PlayerInputEuler
+	pi.move(g, dir)
+}
@@ -0,0 +1,399 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// Actions and axes the built in input components use
+const (
+	ActionMoveLeft  = "move_left"
+	ActionMoveRight = "move_right"
+	ActionMoveUp    = "move_up"
+	ActionMoveDown  = "move_down"
+	ActionJump      = "jump"
+	ActionPause     = "pause"
+
+	AxisMoveX = "move_x"
+	AxisMoveY = "move_y"
+)
+
+// DefaultBindings and DefaultAxes are bound before the [input] section of
+// the settings is read. Binding an action or axis in the settings replaces
+// its defaults
+var (
+	DefaultBindings = []string{
+		"move_left key:Left",
+		"move_right key:Right",
+		"move_up key:Up",
+		"move_down key:Down",
+		"jump key:Up",
+		"pause key:Escape",
+	}
+	DefaultAxes = []string{
+		"move_x key:Left key:Right",
+		"move_x joyaxis:X",
+		"move_y key:Up key:Down",
+		"move_y joyaxis:Y",
+	}
+)
+
+type sourceKind int
+
+const (
+	srcKey sourceKind = iota
+	srcMouse
+	srcJoyButton
+	srcJoyAxis
+)
+
+// Button is a key, mouse button or joystick button, joystick buttons are
+// the same on every joystick
+type Button struct {
+	kind   sourceKind
+	code   int
+	invert bool
+}
+
+func Key(k sf.KeyCode) Button       { return Button{kind: srcKey, code: int(k)} }
+func Mouse(b sf.MouseButton) Button { return Button{kind: srcMouse, code: int(b)} }
+func JoyButton(n uint) Button       { return Button{kind: srcJoyButton, code: int(n)} }
+
+// an axis is either a joystick axis or a pair of buttons for its negative
+// and positive ends
+type axisBinding struct {
+	src      Button
+	neg, pos Button
+	pair     bool
+}
+
+// InputMap binds named actions to keys, mouse buttons and joystick buttons,
+// and named axes to joystick axes or pairs of buttons, so input components
+// ask for "jump" instead of sf.KeyUp and players can remap them.
+//
+// Bindings are written as the action followed by its source:
+//
+//	[input]
+//	bind = jump key:Space
+//	bind = jump joybutton:0
+//	bind = fire mouse:Left
+//	axis = move_x joyaxis:X
+//	axis = move_x key:A key:D
+//	axis = look_y joyaxis:-R
+//
+// Key names are those of the sf.Key constants without the prefix, mouse
+// buttons are Left, Right, Middle, XButton1 and XButton2 and joystick axes
+// are X, Y, Z, R, U, V, PovX and PovY, a leading - inverts them. Gamepad
+// buttons and axes can also go by their standard names, a, b, x, y, lb,
+// rb, back, start, lstick and rstick for buttons and leftx, lefty,
+// rightx, righty, triggers, dpadx and dpady for axes. Joystick bindings
+// match every joystick.
+//
+// Joystick axes ignore anything within the dead zone of their centre,
+// deadzone in the settings sets it as a fraction of the full range and 0
+// turns it off
+type InputMap struct {
+	actions  map[string][]Button
+	axes     map[string][]axisBinding
+	deadZone float32
+}
+
+// NewInputMap creates an input map with the default bindings overridden by
+// those in c's [input] section
+func NewInputMap(c Config) (*InputMap, error) {
+	if c.Input.DeadZone < 0 || c.Input.DeadZone >= 1 {
+		return nil, fmt.Errorf("grout: input deadzone %v isn't between 0 and 1", c.Input.DeadZone)
+	}
+	m := &InputMap{make(map[string][]Button), make(map[string][]axisBinding), float32(c.Input.DeadZone)}
+	for _, b := range DefaultBindings {
+		if err := m.bindLine(b, false, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range DefaultAxes {
+		if err := m.bindLine(b, true, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, b := range c.Input.Bind {
+		if err := m.bindLine(b, false, seen); err != nil {
+			return m, err
+		}
+	}
+	seen = make(map[string]bool)
+	for _, b := range c.Input.Axis {
+		if err := m.bindLine(b, true, seen); err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// bindLine binds a settings line, the first time an action is seen its
+// existing bindings are dropped
+func (m *InputMap) bindLine(line string, axis bool, seen map[string]bool) error {
+	f := strings.Fields(line)
+	if len(f) < 2 {
+		return fmt.Errorf("grout: bad input binding %q", line)
+	}
+	if seen != nil && !seen[f[0]] {
+		seen[f[0]] = true
+		if axis {
+			delete(m.axes, f[0])
+		} else {
+			delete(m.actions, f[0])
+		}
+	}
+	if axis {
+		return m.BindAxis(f[0], f[1:]...)
+	}
+	if len(f) != 2 {
+		return fmt.Errorf("grout: bad input binding %q", line)
+	}
+	return m.Bind(f[0], f[1])
+}
+
+// Bind adds src as another way to trigger action
+func (m *InputMap) Bind(action, src string) error {
+	s, err := parseSource(src)
+	if err != nil {
+		return err
+	}
+	if s.kind == srcJoyAxis {
+		return fmt.Errorf("grout: can't bind joystick axis %q to action %q", src, action)
+	}
+	m.actions[action] = append(m.actions[action], s)
+	return nil
+}
+
+// BindAxis adds a source for the axis name, either a single joystick axis
+// or two buttons for its negative and positive ends
+func (m *InputMap) BindAxis(name string, srcs ...string) error {
+	var b axisBinding
+	switch len(srcs) {
+	case 1:
+		s, err := parseSource(srcs[0])
+		if err != nil {
+			return err
+		}
+		if s.kind != srcJoyAxis {
+			return fmt.Errorf("grout: axis %q needs a joystick axis or two buttons, got %q", name, srcs[0])
+		}
+		b.src = s
+	case 2:
+		var err error
+		if b.neg, err = parseSource(srcs[0]); err != nil {
+			return err
+		}
+		if b.pos, err = parseSource(srcs[1]); err != nil {
+			return err
+		}
+		if b.neg.kind == srcJoyAxis || b.pos.kind == srcJoyAxis {
+			return fmt.Errorf("grout: axis %q can't use joystick axes as buttons", name)
+		}
+		b.pair = true
+	default:
+		return fmt.Errorf("grout: axis %q needs one or two sources", name)
+	}
+	m.axes[name] = append(m.axes[name], b)
+	return nil
+}
+
+// DeadZone returns the fraction of a joystick axis' range around its
+// centre that counts as 0
+func (m *InputMap) DeadZone() float32 { return m.deadZone }
+
+func (m *InputMap) SetDeadZone(dz float32) { m.deadZone = clamp(0, 0.99, dz) }
+
+// HasAction reports whether action has anything bound to it
+func (m *InputMap) HasAction(action string) bool { return len(m.actions[action]) != 0 }
+
+// Unbind removes every binding of an action or axis
+func (m *InputMap) Unbind(name string) {
+	delete(m.actions, name)
+	delete(m.axes, name)
+}
+
+// button returns the button e is for, the joystick it is on and whether
+// it was pressed
+func button(e sf.Event) (b Button, joy uint, pressed, ok bool) {
+	switch ev := e.(type) {
+	case sf.EventKeyPressed:
+		return Key(ev.Code), 0, true, true
+	case sf.EventKeyReleased:
+		return Key(ev.Code), 0, false, true
+	case sf.EventMouseButtonPressed:
+		return Mouse(ev.Button), 0, true, true
+	case sf.EventMouseButtonReleased:
+		return Mouse(ev.Button), 0, false, true
+	case sf.EventJoystickButtonPressed:
+		return JoyButton(ev.Button), ev.JoystickId, true, true
+	case sf.EventJoystickButtonReleased:
+		return JoyButton(ev.Button), ev.JoystickId, false, true
+	}
+	return Button{}, 0, false, false
+}
+
+func (m *InputMap) bound(action string, s Button) bool {
+	for _, b := range m.actions[action] {
+		if b == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Pressed reports whether e presses one of action's buttons
+func (m *InputMap) Pressed(action string, e sf.Event) bool {
+	s, _, pressed, ok := button(e)
+	return ok && pressed && m.bound(action, s)
+}
+
+// Released reports whether e releases one of action's buttons
+func (m *InputMap) Released(action string, e sf.Event) bool {
+	s, _, pressed, ok := button(e)
+	return ok && !pressed && m.bound(action, s)
+}
+
+// AxisMoved reports whether e moves the axis name and the value it moved
+// to, between -1 and 1. Pressing the button for either end of a pair moves
+// the axis all the way there and releasing it moves it back to 0
+func (m *InputMap) AxisMoved(name string, e sf.Event) (float32, bool) {
+	if ev, ok := e.(sf.EventJoystickMoved); ok {
+		for _, b := range m.axes[name] {
+			if !b.pair && b.src.code == int(ev.Axis) {
+				v := deadZone(clamp(-1, 1, ev.Position/100), m.deadZone)
+				if b.src.invert {
+					v = -v
+				}
+				return v, true
+			}
+		}
+		return 0, false
+	}
+
+	s, _, pressed, ok := button(e)
+	if !ok {
+		return 0, false
+	}
+	for _, b := range m.axes[name] {
+		if !b.pair {
+			continue
+		}
+		switch {
+		case !pressed && (b.neg == s || b.pos == s):
+			return 0, true
+		case b.neg == s:
+			return -1, true
+		case b.pos == s:
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+func parseSource(src string) (Button, error) {
+	i := strings.Index(src, ":")
+	if i < 0 {
+		return Button{}, fmt.Errorf("grout: bad input source %q, want kind:name", src)
+	}
+	kind, name := strings.ToLower(src[:i]), src[i+1:]
+	switch kind {
+	case "key":
+		if k, ok := keyNames[strings.ToLower(name)]; ok {
+			return Button{kind: srcKey, code: int(k)}, nil
+		}
+	case "mouse":
+		if b, ok := mouseNames[strings.ToLower(name)]; ok {
+			return Button{kind: srcMouse, code: int(b)}, nil
+		}
+	case "joybutton":
+		if n, ok := padButtonNames[strings.ToLower(name)]; ok {
+			return JoyButton(n), nil
+		}
+		if n, err := strconv.Atoi(name); err == nil && n >= 0 && n < sf.JoystickButtonCount {
+			return Button{kind: srcJoyButton, code: n}, nil
+		}
+	case "joyaxis":
+		s := Button{kind: srcJoyAxis}
+		if strings.HasPrefix(name, "-") {
+			s.invert, name = true, name[1:]
+		}
+		if a, ok := axisNames[strings.ToLower(name)]; ok {
+			s.code = int(a)
+			return s, nil
+		}
+		if a, ok := padAxisNames[strings.ToLower(name)]; ok {
+			s.code = int(a)
+			return s, nil
+		}
+	default:
+		return Button{}, fmt.Errorf("grout: unknown input kind %q in %q", kind, src)
+	}
+	return Button{}, fmt.Errorf("grout: unknown %s %q", kind, name)
+}
+
+var mouseNames = map[string]sf.MouseButton{
+	"left":     sf.MouseLeft,
+	"right":    sf.MouseRight,
+	"middle":   sf.MouseMiddle,
+	"xbutton1": sf.MouseXButton1,
+	"xbutton2": sf.MouseXButton2,
+}
+
+var axisNames = map[string]sf.JoystickAxis{
+	"x":    sf.JoystickX,
+	"y":    sf.JoystickY,
+	"z":    sf.JoystickZ,
+	"r":    sf.JoystickR,
+	"u":    sf.JoystickU,
+	"v":    sf.JoystickV,
+	"povx": sf.JoystickPovX,
+	"povy": sf.JoystickPovY,
+}
+
+var keyNames = map[string]sf.KeyCode{
+	"a": sf.KeyA, "b": sf.KeyB, "c": sf.KeyC, "d": sf.KeyD, "e": sf.KeyE,
+	"f": sf.KeyF, "g": sf.KeyG, "h": sf.KeyH, "i": sf.KeyI, "j": sf.KeyJ,
+	"k": sf.KeyK, "l": sf.KeyL, "m": sf.KeyM, "n": sf.KeyN, "o": sf.KeyO,
+	"p": sf.KeyP, "q": sf.KeyQ, "r": sf.KeyR, "s": sf.KeyS, "t": sf.KeyT,
+	"u": sf.KeyU, "v": sf.KeyV, "w": sf.KeyW, "x": sf.KeyX, "y": sf.KeyY,
+	"z": sf.KeyZ,
+
+	"num0": sf.KeyNum0, "num1": sf.KeyNum1, "num2": sf.KeyNum2, "num3": sf.KeyNum3,
+	"num4": sf.KeyNum4, "num5": sf.KeyNum5, "num6": sf.KeyNum6, "num7": sf.KeyNum7,
+	"num8": sf.KeyNum8, "num9": sf.KeyNum9,
+
+	"escape": sf.KeyEscape, "lcontrol": sf.KeyLControl, "lshift": sf.KeyLShift,
+	"lalt": sf.KeyLAlt, "lsystem": sf.KeyLSystem, "rcontrol": sf.KeyRControl,
+	"rshift": sf.KeyRShift, "ralt": sf.KeyRAlt, "rsystem": sf.KeyRSystem,
+	"menu": sf.KeyMenu, "lbracket": sf.KeyLBracket, "rbracket": sf.KeyRBracket,
+	"semicolon": sf.KeySemiColon, "comma": sf.KeyComma, "period": sf.KeyPeriod,
+	"quote": sf.KeyQuote, "slash": sf.KeySlash, "backslash": sf.KeyBackSlash,
+	"tilde": sf.KeyTilde, "equal": sf.KeyEqual, "dash": sf.KeyDash,
+	"space": sf.KeySpace, "return": sf.KeyReturn, "back": sf.KeyBack,
+	"tab": sf.KeyTab, "pageup": sf.KeyPageUp, "pagedown": sf.KeyPageDown,
+	"end": sf.KeyEnd, "home": sf.KeyHome, "insert": sf.KeyInsert,
+	"delete": sf.KeyDelete, "add": sf.KeyAdd, "subtract": sf.KeySubtract,
+	"multiply": sf.KeyMultiply, "divide": sf.KeyDivide, "pause": sf.KeyPause,
+
+	"left": sf.KeyLeft, "right": sf.KeyRight, "up": sf.KeyUp, "down": sf.KeyDown,
+
+	"numpad0": sf.KeyNumpad0, "numpad1": sf.KeyNumpad1, "numpad2": sf.KeyNumpad2,
+	"numpad3": sf.KeyNumpad3, "numpad4": sf.KeyNumpad4, "numpad5": sf.KeyNumpad5,
+	"numpad6": sf.KeyNumpad6, "numpad7": sf.KeyNumpad7, "numpad8": sf.KeyNumpad8,
+	"numpad9": sf.KeyNumpad9,
+
+	"f1": sf.KeyF1, "f2": sf.KeyF2, "f3": sf.KeyF3, "f4": sf.KeyF4, "f5": sf.KeyF5,
+	"f6": sf.KeyF6, "f7": sf.KeyF7, "f8": sf.KeyF8, "f9": sf.KeyF9, "f10": sf.KeyF10,
+	"f11": sf.KeyF11, "f12": sf.KeyF12, "f13": sf.KeyF13, "f14": sf.KeyF14, "f15": sf.KeyF15,
+}
@@ -0,0 +1,240 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"time"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+type heldKey struct {
+	b   Button
+	joy uint
+}
+
+type joyAxisKey struct {
+	joy  uint
+	axis sf.JoystickAxis
+}
+
+// InputState is a snapshot of which buttons are held and where the
+// joystick axes are, built by the engine from every event at the start of
+// each update step. Unlike the events themselves it can't be consumed or
+// missed, so a state that was paused or had its events swallowed still
+// sees a released key as released.
+//
+// Losing the window's focus releases everything, since the release events
+// would go to another window
+type InputState struct {
+	tm       TaskManager
+	down     map[heldKey]time.Duration
+	pressed  map[heldKey]bool
+	released map[heldKey]bool
+	axes     map[joyAxisKey]float32
+	joys     map[uint]bool
+}
+
+func newInputState(tm TaskManager) *InputState {
+	return &InputState{tm, make(map[heldKey]time.Duration), make(map[heldKey]bool), make(map[heldKey]bool), make(map[joyAxisKey]float32), make(map[uint]bool)}
+}
+
+// update applies the events of a new update step
+func (s *InputState) update(evs Events) {
+	now := s.tm.GameTime()
+	for k := range s.pressed {
+		delete(s.pressed, k)
+	}
+	for k := range s.released {
+		delete(s.released, k)
+	}
+
+	for _, e := range evs {
+		switch ev := e.Event.(type) {
+		case sf.EventLostFocus:
+			s.releaseAll(func(heldKey) bool { return true })
+			for k := range s.axes {
+				delete(s.axes, k)
+			}
+			continue
+		case sf.EventJoystickConnected:
+			s.joys[ev.JoystickId] = true
+			continue
+		case sf.EventJoystickDisconnected:
+			delete(s.joys, ev.JoystickId)
+			s.releaseAll(func(k heldKey) bool { return k.b.kind == srcJoyButton && k.joy == ev.JoystickId })
+			for k := range s.axes {
+				if k.joy == ev.JoystickId {
+					delete(s.axes, k)
+				}
+			}
+			continue
+		case sf.EventJoystickMoved:
+			s.axes[joyAxisKey{ev.JoystickId, ev.Axis}] = clamp(-1, 1, ev.Position/100)
+			continue
+		}
+
+		b, joy, pressed, ok := button(e.Event)
+		if !ok {
+			continue
+		}
+		k := heldKey{b, joy}
+		_, held := s.down[k]
+		switch {
+		case pressed && !held:
+			s.down[k] = now
+			s.pressed[k] = true
+		case !pressed && held:
+			delete(s.down, k)
+			s.released[k] = true
+		}
+	}
+}
+
+func (s *InputState) releaseAll(match func(heldKey) bool) {
+	for k := range s.down {
+		if match(k) {
+			delete(s.down, k)
+			s.released[k] = true
+		}
+	}
+}
+
+// eachJoystick calls f for every joystick the button could be on, until
+// it returns true
+func eachJoystick(b Button, f func(heldKey) bool) bool {
+	if b.kind != srcJoyButton {
+		return f(heldKey{b, 0})
+	}
+	for j := uint(0); j < sf.JoystickCount; j++ {
+		if f(heldKey{b, j}) {
+			return true
+		}
+	}
+	return false
+}
+
+// ButtonDown reports whether b is held
+func (s *InputState) ButtonDown(b Button) bool {
+	return eachJoystick(b, func(k heldKey) bool { _, ok := s.down[k]; return ok })
+}
+
+// ButtonJustPressed reports whether b went down during this update step
+func (s *InputState) ButtonJustPressed(b Button) bool {
+	return eachJoystick(b, func(k heldKey) bool { return s.pressed[k] })
+}
+
+// ButtonJustReleased reports whether b came up during this update step
+func (s *InputState) ButtonJustReleased(b Button) bool {
+	return eachJoystick(b, func(k heldKey) bool { return s.released[k] })
+}
+
+// ButtonHeldFor returns how much game time b has been held, or 0
+func (s *InputState) ButtonHeldFor(b Button) time.Duration {
+	var d time.Duration
+	eachJoystick(b, func(k heldKey) bool {
+		if at, ok := s.down[k]; ok && s.tm.GameTime()-at > d {
+			d = s.tm.GameTime() - at
+		}
+		return false
+	})
+	return d
+}
+
+// JoyButtonDown reports whether button n of joystick joy is held, where
+// ButtonDown would check every joystick
+func (s *InputState) JoyButtonDown(joy, n uint) bool {
+	_, ok := s.down[heldKey{JoyButton(n), joy}]
+	return ok
+}
+
+// JoyButtonJustPressed reports whether button n of joystick joy went down
+// during this update step
+func (s *InputState) JoyButtonJustPressed(joy, n uint) bool {
+	return s.pressed[heldKey{JoyButton(n), joy}]
+}
+
+// JoyButtonJustReleased reports whether button n of joystick joy came up
+// during this update step
+func (s *InputState) JoyButtonJustReleased(joy, n uint) bool {
+	return s.released[heldKey{JoyButton(n), joy}]
+}
+
+// JoyAxis returns the position of a joystick's axis between -1 and 1, with
+// the InputMap's dead zone applied
+func (s *InputState) JoyAxis(joy uint, a sf.JoystickAxis) float32 {
+	return deadZone(s.axes[joyAxisKey{joy, a}], s.tm.Input().DeadZone())
+}
+
+func (s *InputState) anyBound(action string, f func(Button) bool) bool {
+	for _, b := range s.tm.Input().actions[action] {
+		if f(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDown reports whether any of action's buttons is held
+func (s *InputState) IsDown(action string) bool { return s.anyBound(action, s.ButtonDown) }
+
+// JustPressed reports whether one of action's buttons went down during
+// this update step. A quick tap can be both just pressed and just released
+func (s *InputState) JustPressed(action string) bool { return s.anyBound(action, s.ButtonJustPressed) }
+
+// JustReleased reports whether one of action's buttons came up during this
+// update step
+func (s *InputState) JustReleased(action string) bool {
+	return s.anyBound(action, s.ButtonJustReleased)
+}
+
+// HeldFor returns how long the longest held of action's buttons has been
+// held, or 0 if none are
+func (s *InputState) HeldFor(action string) time.Duration {
+	var d time.Duration
+	for _, b := range s.tm.Input().actions[action] {
+		if h := s.ButtonHeldFor(b); h > d {
+			d = h
+		}
+	}
+	return d
+}
+
+// Axis returns the position of the named axis between -1 and 1. With
+// several bindings the one pushed furthest wins
+func (s *InputState) Axis(name string) float32 {
+	var v float32
+	for _, b := range s.tm.Input().axes[name] {
+		var a float32
+		if b.pair {
+			if s.ButtonDown(b.pos) {
+				a++
+			}
+			if s.ButtonDown(b.neg) {
+				a--
+			}
+		} else {
+			for j := uint(0); j < sf.JoystickCount; j++ {
+				if p := s.JoyAxis(j, sf.JoystickAxis(b.src.code)); abs(p) > abs(a) {
+					a = p
+				}
+			}
+			if b.src.invert {
+				a = -a
+			}
+		}
+		if abs(a) > abs(v) {
+			v = a
+		}
+	}
+	return v
+}
+
+func abs(f float32) float32 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
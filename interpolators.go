@@ -4,15 +4,11 @@
 
 package grout
 
-import (
-	"time"
-)
-
-func interpolatorUpdater(pri int) *listTask {
-	return &listTask{BasicTask: NewBasicTask(pri), f: func(l ListItem) {
+func interpolatorUpdater(tm TaskManager, pri int) *listTask {
+	return &listTask{BasicTask: NewBasicTask(pri), name: "interpolators", f: func(l ListItem) {
 		it := l.(Interpolator)
 		if !it.IsFrozen() {
-			it.Update(float32(time.Since(timerUpdate.t).Seconds() * 1000.0))
+			it.Update(float32(tm.ElpsTime().Seconds() * 1000.0))
 		}
 	}}
 }
@@ -43,9 +39,30 @@ type timeBasedInterpolator struct {
 
 func (t *timeBasedInterpolator) Kill()             { t.alive = false }
 func (t *timeBasedInterpolator) IsAlive() bool     { return t.alive }
+func (t *timeBasedInterpolator) IsFrozen() bool    { return t.frozen }
 func (t *timeBasedInterpolator) Freeze()           { t.frozen = true }
 func (t *timeBasedInterpolator) Thaw()             { t.frozen = false }
 func (t *timeBasedInterpolator) GetValue() float32 { return t.val }
+func (t *timeBasedInterpolator) Duration() float32 { return t.totTime }
+
+// Seekable is an Interpolator that runs for a known time and can be put at
+// any point of it, which is what a Timeline needs of what it plays.
+//
+// Duration is how long it runs in milliseconds. SetTime puts it ms into its
+// run, setting its value as an Update reaching there would, whether that is
+// forwards or backwards from where it was
+type Seekable interface {
+	Interpolator
+	Duration() float32
+	SetTime(ms float32)
+}
+
+// seek puts t at ms and brings it back to life, update then works out the
+// value for that time
+func (t *timeBasedInterpolator) seek(ms float32, update func(float32)) {
+	t.elpTime, t.alive = ms, true
+	update(0)
+}
 
 type LinearTimeInterpolator struct {
 	timeBasedInterpolator
@@ -69,6 +86,8 @@ func clamp(min, max, val float32) float32 {
 	}
 }
 
+func (l *LinearTimeInterpolator) SetTime(ms float32) { l.seek(ms, l.Update) }
+
 func (l *LinearTimeInterpolator) Update(dT float32) {
 	l.elpTime += dT
 
@@ -89,6 +108,8 @@ func NewQuadraticTimeInterpolator(timespan, start, end, mid float32) *QuadraticT
 	return &QuadraticTimeInterpolator{timeBasedInterpolator{false, 0, timespan, true, 0}, start, end, mid}
 }
 
+func (q *QuadraticTimeInterpolator) SetTime(ms float32) { q.seek(ms, q.Update) }
+
 func (q *QuadraticTimeInterpolator) Update(dT float32) {
 	q.elpTime += dT
 
@@ -110,6 +131,8 @@ func NewCubicTimeInterpolator(timespan, start, end, mid1, mid2 float32) *CubicTi
 	return &CubicTimeInterpolator{timeBasedInterpolator{false, 0, timespan, true, 0}, start, end, mid1, mid2}
 }
 
+func (c *CubicTimeInterpolator) SetTime(ms float32) { c.seek(ms, c.Update) }
+
 func (c *CubicTimeInterpolator) Update(dT float32) {
 	c.elpTime += dT
 
@@ -121,3 +144,39 @@ func (c *CubicTimeInterpolator) Update(dT float32) {
 		c.Kill()
 	}
 }
+
+// EasedTimeInterpolator goes from the start value to the end value over
+// timespan milliseconds following an easing curve, so
+//
+//	NewEasedTimeInterpolator(500, 0, 100, grout.EaseOutBounce)
+//
+// drops from 0 to 100 and bounces on it. Easings that overshoot, like back
+// and elastic, take the value past the ends on the way
+type EasedTimeInterpolator struct {
+	timeBasedInterpolator
+	startVal, endVal float32
+	ease             Easing
+}
+
+func NewEasedTimeInterpolator(timespan, start, end float32, ease Easing) *EasedTimeInterpolator {
+	if ease == nil {
+		ease = Linear
+	}
+	return &EasedTimeInterpolator{timeBasedInterpolator{false, 0, timespan, true, start}, start, end, ease}
+}
+
+func (e *EasedTimeInterpolator) SetTime(ms float32) { e.seek(ms, e.Update) }
+
+func (e *EasedTimeInterpolator) Update(dT float32) {
+	e.elpTime += dT
+
+	b := float32(1)
+	if e.totTime > 0 {
+		b = clamp(0, 1, e.elpTime/e.totTime)
+	}
+	e.val = e.startVal + (e.endVal-e.startVal)*e.ease(b)
+
+	if e.elpTime > e.totTime {
+		e.Kill()
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	PhaseUpdate = "update"
+	PhaseDraw   = "draw"
+	PhaseFrame  = "frame"
+)
+
+const (
+	profileWindow  = 240
+	maxTraceEvents = 200000
+)
+
+// NamedTask can be implemented by a Task to give it a readable name in the
+// profiler, otherwise tasks are named after their type
+type NamedTask interface {
+	Name() string
+}
+
+// TaskStats are the timings of one phase of a task over the most recent
+// calls, at most the last 240 of them
+type TaskStats struct {
+	Name     string
+	Priority int
+	Phase    string
+	Count    int
+	Min      time.Duration
+	Avg      time.Duration
+	Max      time.Duration
+	P99      time.Duration
+}
+
+type profileKey struct {
+	name  string
+	pri   int
+	phase string
+}
+
+type sampleRing struct {
+	d    [profileWindow]time.Duration
+	next int
+	n    int
+}
+
+func (r *sampleRing) add(d time.Duration) {
+	r.d[r.next] = d
+	r.next = (r.next + 1) % profileWindow
+	if r.n < profileWindow {
+		r.n++
+	}
+}
+
+type traceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat"`
+	Ph    string                 `json:"ph"`
+	Ts    float64                `json:"ts"`
+	Dur   float64                `json:"dur"`
+	Pid   int                    `json:"pid"`
+	Tid   int                    `json:"tid"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+	start time.Time
+}
+
+// Profiler times every Update and Draw call the TaskManager makes while it
+// is enabled. It keeps rolling statistics per task and phase and a trace of
+// the calls which can be written out in the Chrome trace event format, to
+// be opened in about:tracing or Perfetto.
+//
+// Timings are always taken from the system clock, not the engine's Clock,
+// since they measure how long the calls really took
+type Profiler struct {
+	mu      sync.Mutex
+	enabled bool
+	epoch   time.Time
+	samples map[profileKey]*sampleRing
+	trace   []traceEvent
+	head    int
+	dropped int
+}
+
+func newProfiler(enabled bool) *Profiler {
+	p := &Profiler{samples: make(map[profileKey]*sampleRing)}
+	p.SetEnabled(enabled)
+	return p
+}
+
+func (p *Profiler) Enabled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.enabled
+}
+
+func (p *Profiler) SetEnabled(e bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e && p.epoch.IsZero() {
+		p.epoch = time.Now()
+	}
+	p.enabled = e
+}
+
+// Reset throws away the statistics and trace collected so far
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.samples = make(map[profileKey]*sampleRing)
+	p.trace = nil
+	p.head = 0
+	p.dropped = 0
+	p.epoch = time.Now()
+}
+
+func (p *Profiler) begin() time.Time {
+	if !p.Enabled() {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// taskName is looked up on every call rather than kept, so the profiler
+// holds nothing of a task once it is removed
+func taskName(t Task) string {
+	if nt, ok := t.(NamedTask); ok {
+		return nt.Name()
+	}
+	return reflect.TypeOf(t).String()
+}
+
+// end records a call to the given phase of t which began at start, start
+// is zero if the profiler was disabled when the call began
+func (p *Profiler) end(t Task, phase string, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	d := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	name, pri := PhaseFrame, 0
+	if t != nil {
+		name, pri = taskName(t), t.GetPriority()
+	}
+	k := profileKey{name, pri, phase}
+	r, ok := p.samples[k]
+	if !ok {
+		r = new(sampleRing)
+		p.samples[k] = r
+	}
+	r.add(d)
+
+	ev := traceEvent{Name: name, Cat: phase, Ph: "X", Pid: 1, Tid: 1,
+		Args: map[string]interface{}{"priority": pri}, start: start, Dur: micros(d)}
+	if len(p.trace) < maxTraceEvents {
+		p.trace = append(p.trace, ev)
+		return
+	}
+	p.trace[p.head] = ev
+	p.head = (p.head + 1) % maxTraceEvents
+	p.dropped++
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+// Stats returns the statistics for every task and phase seen, ordered by
+// phase then priority
+func (p *Profiler) Stats() []TaskStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ret := make([]TaskStats, 0, len(p.samples))
+	for k, r := range p.samples {
+		d := make([]time.Duration, r.n)
+		copy(d, r.d[:r.n])
+		sort.Sort(durations(d))
+
+		var sum time.Duration
+		for _, v := range d {
+			sum += v
+		}
+		p99 := (r.n*99+99)/100 - 1
+		ret = append(ret, TaskStats{Name: k.name, Priority: k.pri, Phase: k.phase, Count: r.n,
+			Min: d[0], Max: d[r.n-1], Avg: sum / time.Duration(r.n), P99: d[p99]})
+	}
+	sort.Sort(statsList(ret))
+	return ret
+}
+
+// WriteTrace writes the recorded calls as a Chrome trace event JSON object.
+// Only the most recent calls are kept if there were too many to hold
+func (p *Profiler) WriteTrace(w io.Writer) error {
+	p.mu.Lock()
+	evs := make([]traceEvent, 0, len(p.trace))
+	evs = append(append(evs, p.trace[p.head:]...), p.trace[:p.head]...)
+	epoch, dropped := p.epoch, p.dropped
+	p.mu.Unlock()
+
+	for i := range evs {
+		evs[i].Ts = micros(evs[i].start.Sub(epoch))
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent           `json:"traceEvents"`
+		DisplayTimeUnit string                 `json:"displayTimeUnit"`
+		OtherData       map[string]interface{} `json:"otherData"`
+	}{evs, "ms", map[string]interface{}{"dropped": dropped}})
+}
+
+// WriteTraceFile writes the trace to the named file, see WriteTrace
+func (p *Profiler) WriteTraceFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = p.WriteTrace(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+type statsList []TaskStats
+
+func (s statsList) Len() int      { return len(s) }
+func (s statsList) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s statsList) Less(i, j int) bool {
+	if s[i].Phase != s[j].Phase {
+		return s[i].Phase < s[j].Phase
+	}
+	if s[i].Priority != s[j].Priority {
+		return s[i].Priority < s[j].Priority
+	}
+	return s[i].Name < s[j].Name
+}
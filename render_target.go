@@ -0,0 +1,128 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// RenderTarget is what tasks and game states draw into. *sf.RenderWindow
+// satisfies it, as does the HeadlessTarget which is used when there is no
+// display to open a window on
+type RenderTarget interface {
+	sf.RenderTarget
+	Display()
+}
+
+// DrawCall is a single Draw or DrawPrimitives call recorded by a
+// HeadlessTarget. Depth is how deeply nested the call was, a GameObject
+// drawn by a game state is depth 0 and the sprite it draws is depth 1 and
+// so on. Vertices is only set for DrawPrimitives calls
+type DrawCall struct {
+	Drawer   sf.Drawer
+	States   sf.RenderStates
+	Depth    int
+	Vertices []sf.Vertex
+	Prim     sf.PrimitiveType
+}
+
+// HeadlessTarget is a RenderTarget which doesn't need a display. Instead of
+// rasterizing it records every draw call made between a Clear and a Display
+// so that tests can check what a frame would have drawn.
+//
+// Drawing passes the target on to the drawer the same way the SFML targets
+// do, so composite drawers like GameObject, SpriteObj and Map still run
+// their Draw code, only the SFML primitives end up drawing nothing
+type HeadlessTarget struct {
+	size    sf.Vector2u
+	view    *sf.View
+	defView *sf.View
+	clear   sf.Color
+	depth   int
+	pending []DrawCall
+	last    []DrawCall
+	frames  int
+}
+
+func NewHeadlessTarget(w, h uint) *HeadlessTarget {
+	rect := sf.FloatRect{0, 0, float32(w), float32(h)}
+	return &HeadlessTarget{
+		size:    sf.Vector2u{w, h},
+		view:    sf.NewViewFromRect(rect),
+		defView: sf.NewViewFromRect(rect),
+		pending: make([]DrawCall, 0),
+	}
+}
+
+func (h *HeadlessTarget) SetView(v *sf.View)       { h.view = v.Copy() }
+func (h *HeadlessTarget) GetView() *sf.View        { return h.view.Copy() }
+func (h *HeadlessTarget) GetDefaultView() *sf.View { return h.defView.Copy() }
+func (h *HeadlessTarget) GetSize() sf.Vector2u     { return h.size }
+func (h *HeadlessTarget) PushGLStates()            {}
+func (h *HeadlessTarget) PopGLStates()             {}
+func (h *HeadlessTarget) ResetGLStates()           {}
+func (h *HeadlessTarget) ClearColor() sf.Color     { return h.clear }
+func (h *HeadlessTarget) Frames() int              { return h.frames }
+func (h *HeadlessTarget) Pending() []DrawCall      { return h.pending }
+func (h *HeadlessTarget) LastFrame() []DrawCall    { return h.last }
+func (h *HeadlessTarget) SetSize(size sf.Vector2u) { h.size = size }
+
+// Clear throws away whatever was drawn since the last Display, the same as
+// clearing a window would
+func (h *HeadlessTarget) Clear(c sf.Color) {
+	h.clear = c
+	h.pending = h.pending[:0]
+}
+
+func (h *HeadlessTarget) Draw(d sf.Drawer, rs sf.RenderStates) {
+	h.pending = append(h.pending, DrawCall{Drawer: d, States: rs, Depth: h.depth})
+	h.depth++
+	d.Draw(h, rs)
+	h.depth--
+}
+
+func (h *HeadlessTarget) DrawPrimitives(v []sf.Vertex, p sf.PrimitiveType, rs sf.RenderStates) {
+	verts := make([]sf.Vertex, len(v))
+	copy(verts, v)
+	h.pending = append(h.pending, DrawCall{States: rs, Depth: h.depth, Vertices: verts, Prim: p})
+}
+
+// Display finishes the frame, the calls drawn since the last Clear become
+// what LastFrame returns
+func (h *HeadlessTarget) Display() {
+	h.last = append(h.last[:0], h.pending...)
+	h.pending = h.pending[:0]
+	h.frames++
+}
+
+// MapPixelToCoords converts a pixel to world coordinates using the view's
+// center, size and viewport. View rotation is not taken into account
+func (h *HeadlessTarget) MapPixelToCoords(p sf.Vector2i, v *sf.View) sf.Vector2f {
+	if v == nil {
+		v = h.view
+	}
+	vp, sz, c := h.viewport(v), v.GetSize(), v.GetCenter()
+	nx := (float32(p.X)-vp.Left)/vp.Width*2 - 1
+	ny := (float32(p.Y)-vp.Top)/vp.Height*2 - 1
+	return sf.Vector2f{c.X + nx*sz.X/2, c.Y + ny*sz.Y/2}
+}
+
+// MapCoordsToPixel converts world coordinates to a pixel, it is the inverse
+// of MapPixelToCoords
+func (h *HeadlessTarget) MapCoordsToPixel(p sf.Vector2f, v *sf.View) sf.Vector2i {
+	if v == nil {
+		v = h.view
+	}
+	vp, sz, c := h.viewport(v), v.GetSize(), v.GetCenter()
+	nx := (p.X - c.X) / (sz.X / 2)
+	ny := (p.Y - c.Y) / (sz.Y / 2)
+	return sf.Vector2i{int((nx+1)/2*vp.Width + vp.Left), int((ny+1)/2*vp.Height + vp.Top)}
+}
+
+func (h *HeadlessTarget) viewport(v *sf.View) sf.FloatRect {
+	r := v.GetViewport()
+	w, ht := float32(h.size.X), float32(h.size.Y)
+	return sf.FloatRect{r.Left * w, r.Top * ht, r.Width * w, r.Height * ht}
+}
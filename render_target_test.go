@@ -0,0 +1,85 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"testing"
+	"time"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// quad draws a single square the way the SFML shapes do
+type quad struct{}
+
+func (q *quad) Draw(t sf.RenderTarget, rs sf.RenderStates) {
+	t.DrawPrimitives([]sf.Vertex{
+		{Position: sf.Vector2f{0, 0}},
+		{Position: sf.Vector2f{10, 0}},
+		{Position: sf.Vector2f{10, 10}},
+		{Position: sf.Vector2f{0, 10}},
+	}, sf.PrimitiveQuads, rs)
+}
+
+type quadState struct {
+	q       *quad
+	inits   int
+	updates int
+	quit    bool
+}
+
+func (s *quadState) Init(RenderTarget)     { s.inits++ }
+func (s *quadState) OnPause()              {}
+func (s *quadState) OnResume(RenderTarget) {}
+func (s *quadState) Draw(w RenderTarget)   { w.Draw(s.q, sf.DefaultRenderStates()) }
+func (s *quadState) Update() StateCommand {
+	s.updates++
+	if s.quit {
+		return Quit()
+	}
+	return Stay()
+}
+
+func TestHeadlessSteps(t *testing.T) {
+	clk := NewManualClock()
+	target := NewHeadlessTarget(320, 240)
+	tm := NewEngine(DefaultConfig(), WithClock(clk), WithRenderTarget(target))
+	s := &quadState{q: &quad{}}
+	tm.InitialGameState(s)
+
+	for i := 0; i < 3; i++ {
+		clk.Advance(time.Second / 60)
+		if !tm.Step() {
+			t.Fatalf("step %d ended the engine", i)
+		}
+	}
+	if s.inits != 1 || s.updates != 3 {
+		t.Errorf("state had %d inits and %d updates, want 1 and 3", s.inits, s.updates)
+	}
+	if n := target.Frames(); n != 3 {
+		t.Errorf("%d frames displayed, want 3", n)
+	}
+
+	calls := target.LastFrame()
+	if len(calls) != 2 {
+		t.Fatalf("%d draw calls in the last frame, want 2", len(calls))
+	}
+	if calls[0].Drawer != s.q || calls[0].Depth != 0 {
+		t.Errorf("first call drew %v at depth %d, want the quad at 0", calls[0].Drawer, calls[0].Depth)
+	}
+	if calls[1].Drawer != nil || calls[1].Depth != 1 || calls[1].Prim != sf.PrimitiveQuads || len(calls[1].Vertices) != 4 {
+		t.Errorf("second call %+v, want the quad's 4 vertices at depth 1", calls[1])
+	}
+
+	s.quit = true
+	ended := false
+	for i := 0; i < 3 && !ended; i++ {
+		clk.Advance(time.Second / 60)
+		ended = !tm.Step()
+	}
+	if !ended {
+		t.Error("engine still running after the state quit")
+	}
+}
@@ -0,0 +1,388 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// Recordings start with this, followed by a version byte and the tick rate
+// they were made at as a uvarint, 0 for the variable loop mode. After that
+// each event is its tick as a uvarint delta from the previous event's, a
+// byte for the event type and the event's fields as varints
+const (
+	recordMagic   = "GROUTREC"
+	recordVersion = 1
+)
+
+const (
+	recKeyPressed byte = iota + 1
+	recKeyReleased
+	recTextEntered
+	recMouseMoved
+	recMouseButtonPressed
+	recMouseButtonReleased
+	recMouseWheelMoved
+	recMouseEntered
+	recMouseLeft
+	recJoystickButtonPressed
+	recJoystickButtonReleased
+	recJoystickMoved
+	recJoystickConnected
+	recJoystickDisconnected
+	recLostFocus
+	recGainedFocus
+	recResized
+)
+
+// Recorder writes every event an engine takes in, with the update step it
+// was delivered on, so it can be replayed with NewReplay. Events the
+// recording format doesn't know, like ones a game pushes of its own types,
+// are left out
+type Recorder struct {
+	w    *bufio.Writer
+	c    io.Closer
+	last uint64
+	buf  []byte
+	err  error
+}
+
+// NewRecorder creates a recorder writing to w, see WithRecorder
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: bufio.NewWriter(w), buf: make([]byte, 0, 64)}
+}
+
+// Err returns the first error writing the recording failed with
+func (r *Recorder) Err() error { return r.err }
+
+// Flush writes out anything still buffered
+func (r *Recorder) Flush() error {
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return r.err
+}
+
+func (r *Recorder) close() error {
+	err := r.Flush()
+	if r.c != nil {
+		if cerr := r.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (r *Recorder) write(b []byte) {
+	if r.err == nil {
+		_, r.err = r.w.Write(b)
+	}
+}
+
+func (r *Recorder) header(tickRate uint) {
+	b := append([]byte(recordMagic), recordVersion)
+	r.write(appendUvarint(b, uint64(tickRate)))
+}
+
+func (r *Recorder) record(tick uint64, evs []sf.Event) {
+	for _, e := range evs {
+		b := appendUvarint(r.buf[:0], tick-r.last)
+		b, ok := appendEvent(b, e)
+		if !ok {
+			continue
+		}
+		r.last = tick
+		r.write(b)
+	}
+}
+
+func modBit(v int, bit byte) byte {
+	if v != 0 {
+		return bit
+	}
+	return 0
+}
+
+func hasModBit(b, bit byte) int {
+	if b&bit != 0 {
+		return 1
+	}
+	return 0
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var t [binary.MaxVarintLen64]byte
+	return append(b, t[:binary.PutUvarint(t[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var t [binary.MaxVarintLen64]byte
+	return append(b, t[:binary.PutVarint(t[:], v)]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var t [4]byte
+	binary.LittleEndian.PutUint32(t[:], v)
+	return append(b, t[:]...)
+}
+
+func appendInts(b []byte, vs ...int) []byte {
+	for _, v := range vs {
+		b = appendVarint(b, int64(v))
+	}
+	return b
+}
+
+func appendEvent(b []byte, e sf.Event) ([]byte, bool) {
+	switch ev := e.(type) {
+	case sf.EventKeyPressed:
+		b = appendInts(append(b, recKeyPressed), int(ev.Code))
+		return append(b, modBit(ev.Alt, 1)|modBit(ev.Control, 2)|modBit(ev.Shift, 4)|modBit(ev.System, 8)), true
+	case sf.EventKeyReleased:
+		b = appendInts(append(b, recKeyReleased), int(ev.Code))
+		return append(b, modBit(ev.Alt, 1)|modBit(ev.Control, 2)|modBit(ev.Shift, 4)|modBit(ev.System, 8)), true
+	case sf.EventTextEntered:
+		return appendInts(append(b, recTextEntered), int(ev.Char)), true
+	case sf.EventMouseMoved:
+		return appendInts(append(b, recMouseMoved), ev.X, ev.Y), true
+	case sf.EventMouseButtonPressed:
+		return appendInts(append(b, recMouseButtonPressed), int(ev.Button), ev.X, ev.Y), true
+	case sf.EventMouseButtonReleased:
+		return appendInts(append(b, recMouseButtonReleased), int(ev.Button), ev.X, ev.Y), true
+	case sf.EventMouseWheelMoved:
+		return appendInts(append(b, recMouseWheelMoved), ev.Delta, ev.X, ev.Y), true
+	case sf.EventMouseEntered:
+		return append(b, recMouseEntered), true
+	case sf.EventMouseLeft:
+		return append(b, recMouseLeft), true
+	case sf.EventJoystickButtonPressed:
+		return appendInts(append(b, recJoystickButtonPressed), int(ev.JoystickId), int(ev.Button)), true
+	case sf.EventJoystickButtonReleased:
+		return appendInts(append(b, recJoystickButtonReleased), int(ev.JoystickId), int(ev.Button)), true
+	case sf.EventJoystickMoved:
+		b = appendInts(append(b, recJoystickMoved), int(ev.JoystickId), int(ev.Axis))
+		return appendUint32(b, math.Float32bits(ev.Position)), true
+	case sf.EventJoystickConnected:
+		return appendInts(append(b, recJoystickConnected), int(ev.JoystickId)), true
+	case sf.EventJoystickDisconnected:
+		return appendInts(append(b, recJoystickDisconnected), int(ev.JoystickId)), true
+	case sf.EventLostFocus:
+		return append(b, recLostFocus), true
+	case sf.EventGainedFocus:
+		return append(b, recGainedFocus), true
+	case sf.EventResized:
+		return appendInts(append(b, recResized), int(ev.Width), int(ev.Height)), true
+	}
+	return b, false
+}
+
+type replayFrame struct {
+	tick uint64
+	evs  []sf.Event
+}
+
+// Replay is a recording read back by NewReplay. An engine given one with
+// WithReplay takes its events from it instead of the window, delivering
+// each on the same update step it was recorded on. With the fixed loop
+// mode every update step covers the same game time, so the replayed run
+// ends up exactly where the recorded one did however fast it is run,
+// headless runs included
+type Replay struct {
+	tickRate uint
+	frames   []replayFrame
+	next     int
+}
+
+// NewReplay reads a whole recording from r
+func NewReplay(r io.Reader) (*Replay, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, len(recordMagic)+1)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, fmt.Errorf("grout: reading replay header: %v", err)
+	}
+	if string(head[:len(recordMagic)]) != recordMagic {
+		return nil, errors.New("grout: not a grout recording")
+	}
+	if head[len(recordMagic)] != recordVersion {
+		return nil, fmt.Errorf("grout: unsupported recording version %d", head[len(recordMagic)])
+	}
+	rate, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("grout: reading replay header: %v", err)
+	}
+
+	rp := &Replay{tickRate: uint(rate)}
+	var tick uint64
+	for {
+		d, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return rp, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("grout: reading replay: %v", err)
+		}
+		e, err := readEvent(br)
+		if err != nil {
+			return nil, fmt.Errorf("grout: reading replay event at tick %d: %v", tick+d, err)
+		}
+		tick += d
+		if n := len(rp.frames); n == 0 || rp.frames[n-1].tick != tick {
+			rp.frames = append(rp.frames, replayFrame{tick: tick})
+		}
+		f := &rp.frames[len(rp.frames)-1]
+		f.evs = append(f.evs, e)
+	}
+}
+
+// Done reports whether every event of the replay has been delivered
+func (rp *Replay) Done() bool { return rp.next >= len(rp.frames) }
+
+// Ticks returns the update step the last event was recorded on
+func (rp *Replay) Ticks() uint64 {
+	if len(rp.frames) == 0 {
+		return 0
+	}
+	return rp.frames[len(rp.frames)-1].tick
+}
+
+// take returns the events recorded for tick
+func (rp *Replay) take(tick uint64) []sf.Event {
+	for rp.next < len(rp.frames) && rp.frames[rp.next].tick < tick {
+		rp.next++
+	}
+	if rp.next < len(rp.frames) && rp.frames[rp.next].tick == tick {
+		rp.next++
+		return rp.frames[rp.next-1].evs
+	}
+	return nil
+}
+
+func readInts(r *bufio.Reader, n int) ([]int, error) {
+	vs := make([]int, n)
+	for i := range vs {
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		vs[i] = int(v)
+	}
+	return vs, nil
+}
+
+// how many varints each type of event has, events not listed have none
+var recordInts = map[byte]int{
+	recKeyPressed: 1, recKeyReleased: 1, recTextEntered: 1, recMouseMoved: 2,
+	recMouseButtonPressed: 3, recMouseButtonReleased: 3, recMouseWheelMoved: 3,
+	recJoystickButtonPressed: 2, recJoystickButtonReleased: 2, recJoystickMoved: 2,
+	recJoystickConnected: 1, recJoystickDisconnected: 1, recResized: 2,
+}
+
+func readEvent(r *bufio.Reader) (sf.Event, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := readInts(r, recordInts[typ])
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case recKeyPressed, recKeyReleased:
+		m, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if typ == recKeyPressed {
+			return sf.EventKeyPressed{sf.KeyCode(v[0]), hasModBit(m, 1), hasModBit(m, 2), hasModBit(m, 4), hasModBit(m, 8)}, nil
+		}
+		return sf.EventKeyReleased{sf.KeyCode(v[0]), hasModBit(m, 1), hasModBit(m, 2), hasModBit(m, 4), hasModBit(m, 8)}, nil
+	case recTextEntered:
+		return sf.EventTextEntered{rune(v[0])}, nil
+	case recMouseMoved:
+		return sf.EventMouseMoved{v[0], v[1]}, nil
+	case recMouseButtonPressed:
+		return sf.EventMouseButtonPressed{sf.MouseButton(v[0]), v[1], v[2]}, nil
+	case recMouseButtonReleased:
+		return sf.EventMouseButtonReleased{sf.MouseButton(v[0]), v[1], v[2]}, nil
+	case recMouseWheelMoved:
+		return sf.EventMouseWheelMoved{v[0], v[1], v[2]}, nil
+	case recMouseEntered:
+		return sf.EventMouseEntered{}, nil
+	case recMouseLeft:
+		return sf.EventMouseLeft{}, nil
+	case recJoystickButtonPressed:
+		return sf.EventJoystickButtonPressed{uint(v[0]), uint(v[1])}, nil
+	case recJoystickButtonReleased:
+		return sf.EventJoystickButtonReleased{uint(v[0]), uint(v[1])}, nil
+	case recJoystickMoved:
+		var p [4]byte
+		if _, err := io.ReadFull(r, p[:]); err != nil {
+			return nil, err
+		}
+		return sf.EventJoystickMoved{uint(v[0]), sf.JoystickAxis(v[1]), math.Float32frombits(binary.LittleEndian.Uint32(p[:]))}, nil
+	case recJoystickConnected:
+		return sf.EventJoystickConnected{uint(v[0])}, nil
+	case recJoystickDisconnected:
+		return sf.EventJoystickDisconnected{uint(v[0])}, nil
+	case recLostFocus:
+		return sf.EventLostFocus{}, nil
+	case recGainedFocus:
+		return sf.EventGainedFocus{}, nil
+	case recResized:
+		return sf.EventResized{uint(v[0]), uint(v[1])}, nil
+	}
+	return nil, fmt.Errorf("unknown event type %d", typ)
+}
+
+// openRecordings opens the files the debug settings ask to record to or
+// replay from, unless the engine was given a Recorder or Replay already.
+// Replays have to match the loop settings they were recorded with
+func (tm *taskMgr) openRecordings() error {
+	if f := tm.conf.Debug.Replay; f != "" && tm.replay == nil {
+		r, err := os.Open(f)
+		if err != nil {
+			return err
+		}
+		tm.replay, err = NewReplay(r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if f := tm.conf.Debug.Record; f != "" && tm.rec == nil {
+		w, err := os.Create(f)
+		if err != nil {
+			return err
+		}
+		tm.rec = NewRecorder(w)
+		tm.rec.c = w
+	}
+
+	var rate uint
+	if tm.isFixedStep() {
+		rate = tm.conf.Loop.TickRate
+	}
+	if tm.rec != nil {
+		tm.rec.header(rate)
+	}
+	if tm.replay != nil {
+		if tm.replay.tickRate == 0 {
+			return errors.New("grout: can't replay a recording made with the variable loop mode")
+		}
+		if tm.replay.tickRate != rate {
+			return fmt.Errorf("grout: replay was recorded at %d ticks a second in the fixed loop mode", tm.replay.tickRate)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"time"
+)
+
+// Timer is a handle to a callback scheduled on a Scheduler
+type Timer struct {
+	fn       func()
+	due      time.Duration
+	interval time.Duration
+	frames   int
+	left     int
+	repeat   bool
+	engine   bool // due is in the engine's game time rather than the scheduler's
+	done     bool
+}
+
+// Cancel stops the timer from firing again, it is safe to call from inside
+// the timer's own callback
+func (t *Timer) Cancel() { t.done = true }
+
+// Active reports whether the timer will still fire
+func (t *Timer) Active() bool { return !t.done }
+
+// Scheduler runs callbacks after an amount of game time, at regular
+// intervals or every so many update steps. It is a Trigger, ticked by the
+// engine's trigger task once per update step, so the callbacks run on the
+// update goroutine.
+//
+// Its clock is the engine's game time less any time it spent paused, only
+// At goes by the engine's game time itself. Every engine has one in
+// TaskManager.Scheduler(), and StateScheduler gives each game state one of
+// its own which is paused and resumed along with the state and stopped
+// when it leaves the stack:
+//
+//	func (m *Menu) Init(w grout.RenderTarget) {
+//		grout.StateScheduler(m).Every(500*time.Millisecond, m.blink)
+//	}
+type Scheduler struct {
+	tm     TaskManager
+	now    time.Duration
+	paused bool
+	alive  bool
+	timers []*Timer
+}
+
+// NewScheduler creates a scheduler timed by tm, nil means the default
+// engine. Its clock starts at tm's current game time
+func NewScheduler(tm TaskManager) *Scheduler {
+	tm = orDefault(tm)
+	return &Scheduler{tm: tm, now: tm.GameTime(), alive: true, timers: make([]*Timer, 0)}
+}
+
+func (s *Scheduler) Kill()         { s.alive = false }
+func (s *Scheduler) IsAlive() bool { return s.alive }
+
+// Now returns the scheduler's clock, which leaves out the time it was paused
+func (s *Scheduler) Now() time.Duration { return s.now }
+
+// Pause stops the scheduler's clock, no timers fire until Resume
+func (s *Scheduler) Pause()       { s.paused = true }
+func (s *Scheduler) Resume()      { s.paused = false }
+func (s *Scheduler) Paused() bool { return s.paused }
+
+// CancelAll cancels every timer on the scheduler
+func (s *Scheduler) CancelAll() {
+	for _, t := range s.timers {
+		t.Cancel()
+	}
+}
+
+func (s *Scheduler) add(t *Timer) *Timer {
+	s.timers = append(s.timers, t)
+	return t
+}
+
+// After calls fn once d of game time from now
+func (s *Scheduler) After(d time.Duration, fn func()) *Timer {
+	return s.add(&Timer{fn: fn, due: s.now + d})
+}
+
+// At calls fn once the engine's GameTime reaches gameTime, or on the next
+// update step if it already has. While the scheduler is paused it waits
+// for Resume
+func (s *Scheduler) At(gameTime time.Duration, fn func()) *Timer {
+	return s.add(&Timer{fn: fn, due: gameTime, engine: true})
+}
+
+// Every calls fn each time another d of game time has passed. If an update
+// step covers several intervals fn is called once for each of them. A d of
+// zero or less is treated as every update step
+func (s *Scheduler) Every(d time.Duration, fn func()) *Timer {
+	if d <= 0 {
+		return s.EveryFrames(1, fn)
+	}
+	return s.add(&Timer{fn: fn, due: s.now + d, interval: d, repeat: true})
+}
+
+// EveryFrames calls fn every n update steps
+func (s *Scheduler) EveryFrames(n int, fn func()) *Timer {
+	if n < 1 {
+		n = 1
+	}
+	return s.add(&Timer{fn: fn, frames: n, left: n, repeat: true})
+}
+
+func (s *Scheduler) Tick() {
+	if s.paused {
+		return
+	}
+	s.now += s.tm.ElpsTime()
+
+	// callbacks may schedule more timers, those wait for the next tick
+	cur := s.timers
+	for _, t := range cur {
+		if t.frames > 0 {
+			if t.left--; t.left <= 0 && !t.done {
+				t.left = t.frames
+				t.fn()
+			}
+			continue
+		}
+		now := s.now
+		if t.engine {
+			now = s.tm.GameTime()
+		}
+		for !t.done && t.due <= now {
+			if t.repeat {
+				t.due += t.interval
+			} else {
+				t.done = true
+			}
+			t.fn()
+		}
+	}
+
+	live := s.timers[:0]
+	for _, t := range s.timers {
+		if !t.done {
+			live = append(live, t)
+		}
+	}
+	for i := len(live); i < len(s.timers); i++ {
+		s.timers[i] = nil
+	}
+	s.timers = live
+}
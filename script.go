@@ -0,0 +1,155 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// ScriptTask runs a function as a coroutine which can wait across frames,
+// so sequenced behaviour reads top to bottom instead of being a state
+// machine inside Update:
+//
+//	tm.AddTask(grout.NewScriptTask(tm, 10, func(s *grout.ScriptContext) {
+//		g.Accel.X = grout.WALK_ACCEL
+//		s.Wait(2 * time.Second)
+//		g.Accel.X = 0
+//		s.WaitUntil(func() bool { return sf.KeyboardIsKeyPressed(sf.KeySpace) })
+//		fade := grout.NewLinearTimeInterpolator(500, 255, 0)
+//		tm.RegisterInterpolator(fade)
+//		s.WaitForInterpolator(fade)
+//	}))
+//
+// The function runs on its own goroutine but only ever while the task's
+// Update is blocked waiting for it, so it never races the main loop. The
+// wait conditions are checked from Update, once per update step. The task
+// kills itself once the function returns, and stopping the task early
+// unwinds the function from whichever wait it is in
+type ScriptTask struct {
+	BasicTask
+	tm       TaskManager
+	fn       func(*ScriptContext)
+	resume   chan bool
+	yield    chan struct{}
+	cond     func() bool
+	finished bool
+	panicVal interface{}
+}
+
+// ScriptContext is handed to the function of a ScriptTask, its methods
+// suspend the function until their condition is met
+type ScriptContext struct {
+	s *ScriptTask
+}
+
+// NewScriptTask creates a script task with priority p running fn. The
+// engine tm is used for timing waits, nil means the default one
+func NewScriptTask(tm TaskManager, p int, fn func(*ScriptContext)) *ScriptTask {
+	return &ScriptTask{BasicTask: NewBasicTask(p), tm: tm, fn: fn}
+}
+
+func (s *ScriptTask) Start() error {
+	s.resume = make(chan bool)
+	s.yield = make(chan struct{})
+	s.cond = nil
+	s.finished = false
+	go s.run()
+	return nil
+}
+
+func (s *ScriptTask) run() {
+	defer func() {
+		s.panicVal = recover()
+		s.finished = true
+		close(s.yield)
+	}()
+	if !<-s.resume {
+		return
+	}
+	s.fn(&ScriptContext{s})
+}
+
+func (s *ScriptTask) Update() {
+	if s.finished {
+		return
+	}
+	if s.cond != nil && !s.cond() {
+		return
+	}
+	s.cond = nil
+
+	s.resume <- true
+	<-s.yield
+	if s.finished {
+		s.SetCanKill(true)
+		if s.panicVal != nil {
+			panic(s.panicVal)
+		}
+	}
+}
+
+func (s *ScriptTask) Stop() error {
+	if !s.finished {
+		s.resume <- false
+		<-s.yield
+	}
+	return nil
+}
+
+// Done reports whether the script function has returned
+func (s *ScriptTask) Done() bool { return s.finished }
+
+func (c *ScriptContext) wait(cond func() bool) {
+	c.s.cond = cond
+	c.s.yield <- struct{}{}
+	if !<-c.s.resume {
+		runtime.Goexit()
+	}
+}
+
+// TaskManager returns the engine the script is timed by
+func (c *ScriptContext) TaskManager() TaskManager {
+	return orDefault(c.s.tm)
+}
+
+// Context returns the engine's context, it is cancelled when the engine
+// shuts down so anything the script starts can use it to stop
+func (c *ScriptContext) Context() context.Context {
+	return c.TaskManager().Context()
+}
+
+// WaitFrames suspends the script for n update steps
+func (c *ScriptContext) WaitFrames(n int) {
+	if n <= 0 {
+		return
+	}
+	c.wait(func() bool {
+		n--
+		return n <= 0
+	})
+}
+
+// Wait suspends the script until at least d of game time has passed
+func (c *ScriptContext) Wait(d time.Duration) {
+	tm := c.TaskManager()
+	var elapsed time.Duration
+	c.wait(func() bool {
+		elapsed += tm.ElpsTime()
+		return elapsed >= d
+	})
+}
+
+// WaitUntil suspends the script until f returns true, f is called once
+// per update step from the update goroutine
+func (c *ScriptContext) WaitUntil(f func() bool) {
+	c.wait(f)
+}
+
+// WaitForInterpolator suspends the script until i has been killed
+func (c *ScriptContext) WaitForInterpolator(i Interpolator) {
+	c.wait(func() bool { return !i.IsAlive() })
+}
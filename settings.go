@@ -5,25 +5,70 @@
 package grout
 
 import (
-	"code.google.com/p/gcfg"
+	"os"
 )
 
 type Config struct {
 	Video struct {
-		W   uint `gcfg:"width"`
-		H   uint `gcfg:"height"`
-		FPS uint `gcfg:"fps"`
+		W        uint `gcfg:"width"`
+		H        uint `gcfg:"height"`
+		FPS      uint `gcfg:"fps"`
+		Headless bool `gcfg:"headless"`
 	}
 	Debug struct {
-		PrintFPS     bool `gcfg:"printfps"`
-		ShowSprBound bool `gcfg:"showspritebounds"`
+		PrintFPS     bool   `gcfg:"printfps"`
+		ShowSprBound bool   `gcfg:"showspritebounds"`
+		Profile      bool   `gcfg:"profile"`
+		TraceFile    string `gcfg:"tracefile"`
+		Record       string `gcfg:"record"`
+		Replay       string `gcfg:"replay"`
+		Reload       bool   `gcfg:"reload"`
 	}
 	Paths struct {
 		Res string `gcfg:"resources"`
 		Spr string `gcfg:"sprites"`
 	}
+	Loop struct {
+		Mode     string `gcfg:"mode"`
+		TickRate uint   `gcfg:"tickrate"`
+		MaxTicks uint   `gcfg:"maxticks"`
+	}
+	Input struct {
+		Bind     []string `gcfg:"bind"`
+		Axis     []string `gcfg:"axis"`
+		DeadZone float64  `gcfg:"deadzone"`
+	}
+
+	// the game's own sections, see RegisterSection
+	sections []configSection
+	unknown  []string
+	source   settingsSource
+}
+
+// Loop modes for the [loop] section of the settings.
+//
+// LoopVariable runs every task's Update once per rendered frame with the
+// measured frame time. LoopFixed runs Update at a constant tick rate and
+// hands Draw an interpolation alpha (see TaskManager.Alpha)
+const (
+	LoopVariable = "variable"
+	LoopFixed    = "fixed"
+)
+
+const (
+	defaultTickRate = 60
+	defaultMaxTicks = 5
+)
+
+// LoadConfig reads the settings from the given ini file over the defaults,
+// without looking at the environment or flags, see LoadSettings
+func LoadConfig(filename string) (Config, error) {
+	return loadLayers(filename, false, nil, nil)
 }
 
-func loadSettings(c *Config) error {
-	return gcfg.ReadFileInto(c, "settings.ini")
+// loadSettings loads the default engine's settings from settings.ini in the
+// working directory, if there is one, and the environment
+func loadSettings(c *Config) (err error) {
+	*c, err = loadLayers("settings.ini", true, os.Environ(), nil)
+	return err
 }
@@ -0,0 +1,194 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// how often the settings file is checked for changes
+const reloadInterval = time.Second
+
+// settingsSource remembers how a Config was loaded so it can be loaded the
+// same way again
+type settingsSource struct {
+	path     string
+	optional bool
+	env      []string
+	args     []string
+}
+
+// SettingsChanged is published on the engine's bus when the settings file
+// is edited while the game runs, see the reload debug setting. Fields are
+// the settings which changed and were applied, named section.name like
+// "video.fps". Pending are the ones which changed but only take effect
+// after a restart, such as the loop mode or the input bindings.
+//
+// Every setting of a game section is applied, so a subscriber can just read
+// its section again:
+//
+//	tm.Bus().Subscribe(func(e grout.SettingsChanged) {
+//		if e.Section("audio") {
+//			setVolume(tm.GetSettings().Section("audio").(*Audio))
+//		}
+//	})
+type SettingsChanged struct {
+	Fields  []string
+	Pending []string
+}
+
+// Changed reports whether the named setting was changed and applied
+func (e SettingsChanged) Changed(key string) bool {
+	key = strings.ToLower(key)
+	for _, f := range e.Fields {
+		if f == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Section reports whether any setting of the named section was changed and
+// applied
+func (e SettingsChanged) Section(name string) bool {
+	name = strings.ToLower(name) + "."
+	for _, f := range e.Fields {
+		if strings.HasPrefix(f, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// liveSettings are the engine settings which can change while it runs, the
+// game's own sections always can
+var liveSettings = map[string]bool{
+	"video.width":            true,
+	"video.height":           true,
+	"video.fps":              true,
+	"debug.printfps":         true,
+	"debug.showspritebounds": true,
+	"debug.profile":          true,
+	"debug.tracefile":        true,
+	"debug.reload":           true,
+	"paths.resources":        true,
+	"paths.sprites":          true,
+}
+
+func isLive(key string) bool {
+	return liveSettings[key] || !engineSections()[key[:strings.Index(key, ".")]]
+}
+
+// reloadTask polls the modification time of the settings file the engine
+// was loaded from, applying the changes once it is written
+type reloadTask struct {
+	BasicTask
+	tm      *taskMgr
+	checked time.Time
+	mod     time.Time
+	size    int64
+}
+
+func (r *reloadTask) Start() error {
+	r.checked = r.tm.clock.Now()
+	r.mod, r.size = r.stat()
+	return nil
+}
+
+func (r *reloadTask) Stop() error { return nil }
+
+func (r *reloadTask) stat() (time.Time, int64) {
+	fi, err := os.Stat(r.tm.conf.source.path)
+	if err != nil {
+		return time.Time{}, -1
+	}
+	return fi.ModTime(), fi.Size()
+}
+
+func (r *reloadTask) Update() {
+	if !r.tm.conf.Debug.Reload {
+		return
+	}
+	if now := r.tm.clock.Now(); now.Sub(r.checked) >= reloadInterval {
+		r.checked = now
+	} else {
+		return
+	}
+
+	mod, size := r.stat()
+	if mod.Equal(r.mod) && size == r.size {
+		return
+	}
+	r.mod, r.size = mod, size
+	r.tm.reloadSettings()
+}
+
+// reloadSettings loads the settings again the way they were loaded at the
+// start. The settings which can change while running are copied over the
+// current ones, so pointers from GetSettings and Config.Section stay good,
+// and a bad edit is logged and ignored
+func (tm *taskMgr) reloadSettings() {
+	src := tm.conf.source
+	c, err := loadLayers(src.path, src.optional, src.env, src.args)
+	if err != nil {
+		log.Println("grout: ignoring settings change:", err)
+		return
+	}
+
+	cur := make(map[string]configField)
+	for _, f := range configFields(&tm.conf) {
+		cur[f.key()] = f
+	}
+	var e SettingsChanged
+	for _, f := range configFields(&c) {
+		old, ok := cur[f.key()]
+		if !ok || reflect.DeepEqual(f.v.Interface(), old.v.Interface()) {
+			continue
+		}
+		if !isLive(f.key()) {
+			e.Pending = append(e.Pending, f.key())
+			continue
+		}
+		old.v.Set(f.v)
+		e.Fields = append(e.Fields, f.key())
+	}
+	if len(e.Pending) != 0 {
+		log.Printf("grout: settings %s changed, restart to apply them", strings.Join(e.Pending, ", "))
+	}
+	if len(e.Fields) == 0 && len(e.Pending) == 0 {
+		return
+	}
+
+	tm.applySettings(e)
+	tm.bus.Publish(e)
+}
+
+// applySettings makes the changed settings take effect, the ones that are
+// read where they're used, like the paths, need nothing doing
+func (tm *taskMgr) applySettings(e SettingsChanged) {
+	if e.Changed("video.width") || e.Changed("video.height") {
+		tm.w, tm.h = tm.conf.Video.W, tm.conf.Video.H
+		if tm.win != nil {
+			tm.win.SetSize(sf.Vector2u{tm.w, tm.h})
+		} else if h, ok := tm.target.(*HeadlessTarget); ok {
+			h.SetSize(sf.Vector2u{tm.w, tm.h})
+		}
+	}
+	if e.Changed("video.fps") && tm.win != nil {
+		tm.win.SetFramerateLimit(tm.conf.Video.FPS)
+	}
+	if e.Changed("debug.printfps") {
+		tm.fpsUpdate.p = tm.conf.Debug.PrintFPS
+	}
+	if e.Changed("debug.profile") || e.Changed("debug.tracefile") {
+		tm.prof.SetEnabled(tm.conf.Debug.Profile || tm.conf.Debug.TraceFile != "")
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"math"
+
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// SpringParams describe a damped spring pulling a value towards its target.
+// Stiffness is the force per unit the value is away from the target,
+// Damping the force against it per unit per second it is moving and Mass
+// how heavy the value is, so with seconds as the time unit. Velocities are
+// in units per second, though Update takes its dT in milliseconds like the
+// other interpolators. Tolerance is how close to the target, in units, and
+// how slow, in units per second, the value has to get for the spring to
+// count as settled. A zero Mass is taken as 1 and a zero Tolerance as 0.01.
+//
+// Damping below CriticalDamping(Stiffness, Mass) overshoots and bounces,
+// at it the value gets there as fast as it can without overshooting, and
+// above it creeps in more slowly
+type SpringParams struct {
+	Stiffness float32
+	Damping   float32
+	Mass      float32
+	Tolerance float32
+}
+
+// CriticalDamping is the damping for a spring of the given stiffness and
+// mass that settles fastest without overshooting
+func CriticalDamping(stiffness, mass float32) float32 {
+	return float32(2 * math.Sqrt(float64(stiffness)*float64(mass)))
+}
+
+func (p SpringParams) withDefaults() SpringParams {
+	if p.Mass <= 0 {
+		p.Mass = 1
+	}
+	if p.Tolerance <= 0 {
+		p.Tolerance = 0.01
+	}
+	return p
+}
+
+// step moves a spring with displacement y from its target and velocity v
+// on by dt seconds. It uses the exact solution of the damped oscillator
+// rather than integrating, so it stays stable however long dt is
+func (p SpringParams) step(y, v, dt float64) (float64, float64) {
+	k, c, m := float64(p.Stiffness), float64(p.Damping), float64(p.Mass)
+	if k <= 0 {
+		// nothing pulls it back, only the damping slows it down
+		if c <= 0 {
+			return y + v*dt, v
+		}
+		e := math.Exp(-c / m * dt)
+		return y + v*m/c*(1-e), v * e
+	}
+
+	w := math.Sqrt(k / m)
+	z := c / (2 * math.Sqrt(k*m))
+	switch {
+	case math.Abs(z-1) < 1e-6:
+		e := math.Exp(-w * dt)
+		b := v + w*y
+		return (y + b*dt) * e, (v - w*b*dt) * e
+	case z < 1:
+		wd := w * math.Sqrt(1-z*z)
+		e := math.Exp(-z * w * dt)
+		cos, sin := math.Cos(wd*dt), math.Sin(wd*dt)
+		return e * (y*cos + (v+z*w*y)/wd*sin), e * (v*cos - (z*w*v+w*w*y)/wd*sin)
+	default:
+		s := math.Sqrt(z*z - 1)
+		r1, r2 := -w*(z-s), -w*(z+s)
+		c1 := (v - r2*y) / (r1 - r2)
+		c2 := y - c1
+		e1, e2 := math.Exp(r1*dt), math.Exp(r2*dt)
+		return c1*e1 + c2*e2, c1*r1*e1 + c2*r2*e2
+	}
+}
+
+type springState struct {
+	frozen   bool
+	alive    bool
+	resting  bool
+	onSettle func()
+}
+
+func (s *springState) Kill()          { s.alive = false }
+func (s *springState) IsAlive() bool  { return s.alive }
+func (s *springState) IsFrozen() bool { return s.frozen }
+func (s *springState) Freeze()        { s.frozen = true }
+func (s *springState) Thaw()          { s.frozen = false }
+
+// OnSettle calls fn each time the spring comes to rest on its target. To
+// be done with a spring once it gets there, Kill it from fn
+func (s *springState) OnSettle(fn func()) { s.onSettle = fn }
+
+// settle calls the OnSettle func the first update the spring is at rest
+func (s *springState) settle() {
+	if s.resting {
+		return
+	}
+	s.resting = true
+	if s.onSettle != nil {
+		s.onSettle()
+	}
+}
+
+// Spring is an Interpolator that pulls its value towards a target like a
+// damped spring instead of over a set time. The target can be moved at any
+// time and the value carries on from where it is at the speed it was going,
+// so there is no jump.
+//
+// Once the value is within the tolerance of the target, and barely moving,
+// it is put on the target and OnSettle is called. The spring stays
+// registered at rest, so moving the target again sets it going, until it
+// is killed
+type Spring struct {
+	springState
+	p        SpringParams
+	val, vel float32
+	target   float32
+	set      func(float32)
+}
+
+// NewSpring creates a spring taking a value from start to target, calling
+// set with it every update if set isn't nil:
+//
+//	p := grout.SpringParams{Stiffness: 200, Damping: 10}
+//	tm.RegisterInterpolator(grout.NewSpring(0, 1, p, func(v float32) { obj.SetScale(sf.Vector2f{v, v}) }))
+func NewSpring(start, target float32, p SpringParams, set func(float32)) *Spring {
+	return &Spring{springState{false, true, false, nil}, p.withDefaults(), start, 0, target, set}
+}
+
+func (s *Spring) GetValue() float32    { return s.val }
+func (s *Spring) Velocity() float32    { return s.vel }
+func (s *Spring) Target() float32      { return s.target }
+func (s *Spring) Params() SpringParams { return s.p }
+
+// SetTarget moves where the spring pulls to, keeping its value and velocity
+func (s *Spring) SetTarget(target float32) {
+	s.target = target
+	if !s.Settled() {
+		s.resting = false
+	}
+}
+
+// SetVelocity kicks the value, like an impulse to make something wobble
+func (s *Spring) SetVelocity(v float32) {
+	s.vel = v
+	if !s.Settled() {
+		s.resting = false
+	}
+}
+
+// SetValue snaps the value to v, keeping its velocity
+func (s *Spring) SetValue(v float32) {
+	s.val = v
+	if !s.Settled() {
+		s.resting = false
+	}
+}
+
+// Settled reports whether the value is at rest on the target
+func (s *Spring) Settled() bool {
+	return abs32(s.val-s.target) < s.p.Tolerance && abs32(s.vel) < s.p.Tolerance
+}
+
+func (s *Spring) Update(dT float32) {
+	y, v := s.p.step(float64(s.val-s.target), float64(s.vel), float64(dT)/1000)
+	s.val, s.vel = s.target+float32(y), float32(v)
+	settled := s.Settled()
+	if settled {
+		s.val, s.vel = s.target, 0
+	}
+	if s.set != nil {
+		s.set(s.val)
+	}
+	if settled {
+		s.settle()
+	}
+}
+
+// VectorSpring is a Spring for a 2D value, like a position or a scale. Both
+// axes share the same SpringParams. GetValue is how far the value is from
+// the target, Vector is the value itself
+type VectorSpring struct {
+	springState
+	p        SpringParams
+	val, vel sf.Vector2f
+	target   sf.Vector2f
+	set      func(sf.Vector2f)
+}
+
+// NewVectorSpring creates a spring taking a vector from start to target,
+// calling set with it every update if set isn't nil. For a camera following
+// a player:
+//
+//	cam := grout.NewVectorSpring(view.GetCenter(), player.GetPosition(), p, view.SetCenter)
+//	tm.RegisterInterpolator(cam)
+//	// then every update
+//	cam.SetTarget(player.GetPosition())
+func NewVectorSpring(start, target sf.Vector2f, p SpringParams, set func(sf.Vector2f)) *VectorSpring {
+	return &VectorSpring{springState{false, true, false, nil}, p.withDefaults(), start, sf.Vector2f{}, target, set}
+}
+
+func (s *VectorSpring) Vector() sf.Vector2f   { return s.val }
+func (s *VectorSpring) Velocity() sf.Vector2f { return s.vel }
+func (s *VectorSpring) Target() sf.Vector2f   { return s.target }
+func (s *VectorSpring) Params() SpringParams  { return s.p }
+
+func (s *VectorSpring) GetValue() float32 {
+	d := s.val.Minus(s.target)
+	return float32(math.Hypot(float64(d.X), float64(d.Y)))
+}
+
+// SetTarget moves where the spring pulls to, keeping its value and velocity
+func (s *VectorSpring) SetTarget(target sf.Vector2f) {
+	s.target = target
+	if !s.Settled() {
+		s.resting = false
+	}
+}
+
+// SetVelocity kicks the value, like an impulse to make something wobble
+func (s *VectorSpring) SetVelocity(v sf.Vector2f) {
+	s.vel = v
+	if !s.Settled() {
+		s.resting = false
+	}
+}
+
+// SetValue snaps the value to v, keeping its velocity
+func (s *VectorSpring) SetValue(v sf.Vector2f) {
+	s.val = v
+	if !s.Settled() {
+		s.resting = false
+	}
+}
+
+// Settled reports whether the value is at rest on the target
+func (s *VectorSpring) Settled() bool {
+	d, tol := s.val.Minus(s.target), s.p.Tolerance
+	return abs32(d.X) < tol && abs32(d.Y) < tol && abs32(s.vel.X) < tol && abs32(s.vel.Y) < tol
+}
+
+func (s *VectorSpring) Update(dT float32) {
+	dt := float64(dT) / 1000
+	yx, vx := s.p.step(float64(s.val.X-s.target.X), float64(s.vel.X), dt)
+	yy, vy := s.p.step(float64(s.val.Y-s.target.Y), float64(s.vel.Y), dt)
+	s.val = sf.Vector2f{s.target.X + float32(yx), s.target.Y + float32(yy)}
+	s.vel = sf.Vector2f{float32(vx), float32(vy)}
+	settled := s.Settled()
+	if settled {
+		s.val, s.vel = s.target, sf.Vector2f{}
+	}
+	if s.set != nil {
+		s.set(s.val)
+	}
+	if settled {
+		s.settle()
+	}
+}
+
+func abs32(f float32) float32 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// SmoothDamp moves current towards target the way a critically damped
+// spring would, without overshooting, taking roughly smoothTime
+// milliseconds to get there. It needs no registering, call it every update
+// with the ElpsTime in milliseconds as dT and keep velocity, in units per
+// second, between calls:
+//
+//	var vel float32
+//	// every update
+//	zoom = grout.SmoothDamp(zoom, wantZoom, &vel, 300, dT)
+//
+// As it only looks at where the target is now, the target can move freely
+func SmoothDamp(current, target float32, velocity *float32, smoothTime, dT float32) float32 {
+	if dT <= 0 {
+		return current
+	}
+	if smoothTime < 1 {
+		smoothTime = 1
+	}
+	dt := float64(dT) / 1000
+	omega := 2 / (float64(smoothTime) / 1000)
+	x := omega * dt
+	e := 1 / (1 + x + 0.48*x*x + 0.235*x*x*x)
+
+	change := float64(current - target)
+	temp := (float64(*velocity) + omega*change) * dt
+	vel := (float64(*velocity) - omega*temp) * e
+	out := float64(target) + (change+temp)*e
+
+	// don't overshoot the target
+	if (target > current) == (out > float64(target)) && out != float64(target) {
+		out, vel = float64(target), 0
+	}
+	*velocity = float32(vel)
+	return float32(out)
+}
+
+// SmoothDampVector is SmoothDamp for each axis of a vector
+func SmoothDampVector(current, target sf.Vector2f, velocity *sf.Vector2f, smoothTime, dT float32) sf.Vector2f {
+	return sf.Vector2f{
+		SmoothDamp(current.X, target.X, &velocity.X, smoothTime, dT),
+		SmoothDamp(current.Y, target.Y, &velocity.Y, smoothTime, dT),
+	}
+}
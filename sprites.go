@@ -8,7 +8,7 @@ import (
 	sf "bitbucket.org/krepa098/gosfml2"
 	"encoding/xml"
 	"errors"
-	"log"
+	"fmt"
 	"os"
 )
 
@@ -151,6 +151,39 @@ func NewSpriteObj() *SpriteObj {
 type SpriteObj struct {
 	Animations AnimMap
 	currAnim   *Animation
+	tm         TaskManager
+	color      sf.Color
+	tinted     bool
+}
+
+// SetTaskManager binds the sprite to an engine other than the default one,
+// its settings are used for loading and drawing the animations
+func (s *SpriteObj) SetTaskManager(tm TaskManager) {
+	s.tm = tm
+	for _, a := range s.Animations {
+		a.tm = tm
+	}
+}
+
+// GetColor returns the colour the sprite is drawn with, white unless
+// SetColor changed it
+func (s *SpriteObj) GetColor() sf.Color {
+	if !s.tinted {
+		return sf.ColorWhite()
+	}
+	return s.color
+}
+
+// SetColor tints every cell of every animation with c, and its alpha fades
+// the sprite, so a SpriteObj can be given to NewTintTween and NewAlphaTween.
+// Animations loaded afterwards are tinted too
+func (s *SpriteObj) SetColor(c sf.Color) {
+	s.color, s.tinted = c, true
+	for _, a := range s.Animations {
+		for _, cell := range a.cells {
+			cell.Spr.SetColor(c)
+		}
+	}
 }
 
 func (s *SpriteObj) SetAnim(state SpriteState) {
@@ -167,10 +200,11 @@ type Animation struct {
 	currIndex int
 	cells     []AniCell
 	fc        int
+	tm        TaskManager
 }
 
 func (a *Animation) FlipAnimation() *Animation {
-	anim := &Animation{}
+	anim := &Animation{tm: a.tm}
 	anim.cells = make([]AniCell, len(a.cells))
 	for i, c := range a.cells {
 		c.Spr = c.Spr.Copy()
@@ -181,35 +215,25 @@ func (a *Animation) FlipAnimation() *Animation {
 	return anim
 }
 
+// LoadAnimations reads a darkFunction animation file, and the sprite sheet
+// it refers to, from the sprites path in the settings. Errors say which
+// file failed to load
 func (s *SpriteObj) LoadAnimations(filename string) error {
-	c := GetTaskManager().GetSettings()
+	c := orDefault(s.tm).GetSettings()
 	sprpath := c.Paths.Res + "/" + c.Paths.Spr + "/"
 
-	file, err := os.Open(sprpath + filename)
-	if err != nil {
-		log.Fatal(err)
-		return err
-	}
-	defer file.Close()
-
 	animInfo := &DFEAnimations{}
-	if err = xml.NewDecoder(file).Decode(animInfo); err != nil {
-		log.Fatal(err)
+	if err := decodeXMLFile(sprpath+filename, animInfo); err != nil {
 		return err
 	}
-
-	file.Close()
-	if file, err = os.Open(sprpath + animInfo.SheetFileName); err != nil {
-		log.Fatal(err)
+	if err := decodeXMLFile(sprpath+animInfo.SheetFileName, &animInfo.Sheet); err != nil {
 		return err
 	}
 
-	if err = xml.NewDecoder(file).Decode(&animInfo.Sheet); err != nil {
-		log.Fatal(err)
-		return err
+	var err error
+	if animInfo.Sheet.Texture, err = sf.NewTextureFromFile(sprpath+animInfo.Sheet.Img, nil); err != nil {
+		return fmt.Errorf("loading texture %s: %v", sprpath+animInfo.Sheet.Img, err)
 	}
-
-	animInfo.Sheet.Texture, err = sf.NewTextureFromFile(sprpath+animInfo.Sheet.Img, nil)
 	for _, v := range animInfo.Sheet.Defs.Defs {
 		if v.Spr, err = sf.NewSprite(animInfo.Sheet.Texture); err != nil {
 			return err
@@ -229,10 +253,14 @@ func (s *SpriteObj) LoadAnimations(filename string) error {
 			state := nameToState(a.Name)
 			anim := s.Animations[state]
 			if anim == nil {
-				anim = &Animation{}
+				anim = &Animation{tm: s.tm}
+			}
+			def, ok := animInfo.Sheet.Defs.Defs[c.Spr.ImgName]
+			if !ok {
+				return fmt.Errorf("%s: animation %q uses unknown sprite %q", sprpath+filename, a.Name, c.Spr.ImgName)
 			}
 			var cell AniCell
-			cell.Spr = animInfo.Sheet.Defs.Defs[c.Spr.ImgName].Spr
+			cell.Spr = def.Spr
 			cell.RenderState = sf.DefaultRenderStates()
 			if c.Spr.FlipH == 1 {
 				cell.Spr = cell.Spr.Copy()
@@ -245,7 +273,23 @@ func (s *SpriteObj) LoadAnimations(filename string) error {
 			s.Animations[state] = anim
 		}
 	}
+	if s.tinted {
+		s.SetColor(s.color)
+	}
+
+	return nil
+}
+
+func decodeXMLFile(name string, v interface{}) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
+	if err = xml.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("decoding %s: %v", name, err)
+	}
 	return nil
 }
 
@@ -277,7 +321,7 @@ func (a *Animation) Draw(target sf.RenderTarget, renderStates sf.RenderStates) {
 	// log.Println(renderStates.Transform)
 	target.Draw(a.cells[a.currIndex].Spr, renderStates)
 
-	if GetTaskManager().GetSettings().Debug.ShowSprBound {
+	if orDefault(a.tm).GetSettings().Debug.ShowSprBound {
 		rs, _ := sf.NewRectangleShape()
 		rs.SetSize(sf.Vector2f{gb.Width, gb.Height})
 		rs.SetPosition(sf.Vector2f{gb.Left, gb.Top})
@@ -0,0 +1,134 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+type stateOp int
+
+const (
+	opStay stateOp = iota
+	opPush
+	opPop
+	opReplace
+	opPopN
+	opPopTo
+	opClearAndPush
+	opQuit
+)
+
+// StateCommand is returned from a CommandState's Update to change the state
+// stack. The zero value, also returned by Stay, leaves the stack alone.
+//
+// The lifecycle calls made for each command are exact: a state gets
+// OnPause when something is pushed over it, OnResume when it becomes the
+// top again, and OnExit (if it implements Exiter) when it leaves the stack.
+// States that never change position get no calls at all. A change played
+// With a transition still draws the states it removes, so their OnExit
+// waits until the transition is over
+type StateCommand struct {
+	op    stateOp
+	state CommandState
+	n     int
+	pred  func(CommandState) bool
+	trans Transition
+}
+
+// Stay keeps the current state on top
+func Stay() StateCommand { return StateCommand{} }
+
+// Push pauses the current state and puts s on top of it
+func Push(s CommandState) StateCommand { return StateCommand{op: opPush, state: s} }
+
+// Pop removes the current state and resumes the one beneath it, popping
+// the last state ends the game
+func Pop() StateCommand { return StateCommand{op: opPop, n: 1} }
+
+// Replace removes the current state and puts s in its place, the state
+// beneath stays paused
+func Replace(s CommandState) StateCommand { return StateCommand{op: opReplace, state: s} }
+
+// PopN removes the top n states and resumes the one beneath them
+func PopN(n int) StateCommand { return StateCommand{op: opPopN, n: n} }
+
+// PopTo removes states until the top one satisfies pred, which is then
+// resumed. If none does, or pred is nil, everything but the bottom state
+// is removed
+func PopTo(pred func(CommandState) bool) StateCommand { return StateCommand{op: opPopTo, pred: pred} }
+
+// ClearAndPush removes every state and starts over with s
+func ClearAndPush(s CommandState) StateCommand { return StateCommand{op: opClearAndPush, state: s} }
+
+// Quit removes every state and ends the game
+func Quit() StateCommand { return StateCommand{op: opQuit} }
+
+// With plays t while the command's change takes place
+func (c StateCommand) With(t Transition) StateCommand {
+	c.trans = t
+	return c
+}
+
+// CommandState is a game state whose Update says how the stack should
+// change. It is what the state stack holds, a GameState goes on it through
+// FromGameState
+type CommandState interface {
+	Init(RenderTarget)
+	Update() StateCommand
+	Draw(RenderTarget)
+	OnPause()
+	OnResume(RenderTarget)
+}
+
+// Exiter can be implemented by a state to be told when it is removed from
+// the stack, to release whatever Init set up
+type Exiter interface {
+	OnExit()
+}
+
+// legacyState adapts a GameState to a CommandState
+type legacyState struct {
+	GameState
+}
+
+// FromGameState adapts a GameState with the original two value Update so it
+// can go wherever a CommandState does:
+//
+//	tm.InitialGameState(grout.FromGameState(&menu))
+//
+// The states its Update returns are adapted the same way, and a state plus
+// pop is treated as Replace. Adapting a state twice gives equal values, so
+// either can be passed to SubscribeState
+func FromGameState(g GameState) CommandState {
+	if g == nil {
+		return nil
+	}
+	return legacyState{g}
+}
+
+func (l legacyState) Update() StateCommand {
+	next, pop := l.GameState.Update()
+	var trans Transition
+	if w, ok := next.(*transitioned); ok {
+		next, trans = w.GameState, w.t
+	}
+
+	var cmd StateCommand
+	switch s := FromGameState(next); {
+	case s != nil && pop:
+		cmd = Replace(s)
+	case s != nil:
+		cmd = Push(s)
+	case pop:
+		cmd = Pop()
+	}
+	return cmd.With(trans)
+}
+
+// stateValue is the state s was made from, looking through FromGameState,
+// for checking what else it implements
+func stateValue(s CommandState) interface{} {
+	if l, ok := s.(legacyState); ok {
+		return l.GameState
+	}
+	return s
+}
@@ -6,7 +6,7 @@ package grout
 
 import (
 	sf "bitbucket.org/krepa098/gosfml2"
-	"container/list"
+	"context"
 	"log"
 	"runtime"
 	"sort"
@@ -15,17 +15,35 @@ import (
 )
 
 type TaskManager interface {
-	Execute()
-	AddTask(t Task) bool
+	Execute() error
+	AddTask(t Task) error
 	SuspendTask(t Task)
 	RemoveTask(t Task)
 	ResumeTask(t Task)
 	KillAllTasks()
+	Shutdown(err error)
+	Context() context.Context
 	ElpsTime() time.Duration
+	GameTime() time.Duration
+	Ticks() uint64
+	Alpha() float32
+	Clock() Clock
+	Profiler() *Profiler
+	Scheduler() *Scheduler
+	StateScheduler(s CommandState) *Scheduler
+	Input() *InputMap
+	InputState() *InputState
+	Bus() *EventBus
+	Step() bool
+
+	RegisterTrigger(t Trigger)
+	RegisterInterpolator(i Interpolator)
+	InitialGameState(s CommandState)
 
 	GetSettings() *Config
-	getWindow() *sf.RenderWindow
-	GetEventQueue() *list.List
+	getTarget() RenderTarget
+	Events() Events
+	PushEvent(e sf.Event)
 }
 
 type taskList []Task
@@ -66,90 +84,215 @@ type taskMgr struct {
 	taskList       taskList
 	pausedTaskList taskList
 	conf           Config
-	prev           time.Time
+	title          string
+	clock          Clock
+	prof           *Profiler
+	last           time.Time
+	dt             time.Duration
+	gameTime       time.Duration
+	tick           uint64
+	step           time.Duration
+	acc            time.Duration
+	alpha          float32
 	win            *sf.RenderWindow
+	target         RenderTarget
 	w              uint
 	h              uint
-	evQue          *list.List
+	pending        []sf.Event
 	queMutex       sync.Mutex
-	// ticker         *time.Ticker
+	events         Events
+	stateEvents    Events
+	inState        bool
+	parent         context.Context
+	ctx            context.Context
+	cancel         context.CancelFunc
+	err            error
+	errMutex       sync.Mutex
+
+	vidUpdate     *SimpleTask
+	stateUpdate   *gameStateTask
+	fpsUpdate     *fpsTask
+	interUpdate   *listTask
+	triggerUpdate *listTask
+	sched         *Scheduler
+	input         *InputMap
+	inputState    *InputState
+	rec           *Recorder
+	replay        *Replay
+	bus           *EventBus
 }
 
-func newTaskMgr() *taskMgr {
-	t := &taskMgr{taskList: make([]Task, 0), pausedTaskList: make([]Task, 0)}
-	if err := loadSettings(&t.conf); err != nil {
-		panic("Failed to load settings")
+// EngineOption changes how NewEngine sets up an engine
+type EngineOption func(*taskMgr)
+
+// WithRenderTarget makes the engine draw into t instead of opening a
+// window, Execute then runs without polling any window events
+func WithRenderTarget(t RenderTarget) EngineOption {
+	return func(tm *taskMgr) { tm.target = t }
+}
+
+// WithClock makes the engine read the time from c instead of the system
+// clock, see ManualClock
+func WithClock(c Clock) EngineOption {
+	return func(tm *taskMgr) { tm.clock = c }
+}
+
+// WithContext ties the engine to ctx, cancelling it shuts the engine down
+// and Execute returns ctx's error
+func WithContext(ctx context.Context) EngineOption {
+	return func(tm *taskMgr) { tm.parent = ctx }
+}
+
+// WithRecorder records every event the engine takes in to r, see Recorder
+func WithRecorder(r *Recorder) EngineOption {
+	return func(tm *taskMgr) { tm.rec = r }
+}
+
+// WithReplay makes the engine take its events from rp instead of the
+// window, see Replay
+func WithReplay(rp *Replay) EngineOption {
+	return func(tm *taskMgr) { tm.replay = rp }
+}
+
+// WithTitle sets the title of the window Execute opens
+func WithTitle(title string) EngineOption {
+	return func(tm *taskMgr) { tm.title = title }
+}
+
+// NewEngine creates a TaskManager with the given settings, any engine
+// setting left at zero takes its value from DefaultConfig. Every engine owns
+// its own tasks, game state stack, interpolators and triggers so several
+// can exist side by side
+func NewEngine(cfg Config, opts ...EngineOption) TaskManager {
+	t := &taskMgr{taskList: make([]Task, 0), pausedTaskList: make([]Task, 0), conf: cfg.withDefaults(), title: "Grout", clock: RealClock(), parent: context.Background()}
+	for _, o := range opts {
+		o(t)
 	}
+	t.ctx, t.cancel = context.WithCancel(t.parent)
 
 	t.w = t.conf.Video.W
 	t.h = t.conf.Video.H
-	// t.ticker = time.NewTicker(time.Second / t.conf.Video.FPS)
+	t.step = time.Second / time.Duration(t.conf.Loop.TickRate)
+	t.alpha = 1
+	t.prof = newProfiler(t.conf.Debug.Profile || t.conf.Debug.TraceFile != "")
+
+	t.vidUpdate = &SimpleTask{NewBasicTask(1000), nil, func(w RenderTarget) { w.Display() }}
+	t.stateUpdate = &gameStateTask{BasicTask: NewBasicTask(500), tm: t}
+	t.fpsUpdate = &fpsTask{BasicTask: NewBasicTask(1), p: t.conf.Debug.PrintFPS, clk: t.clock}
+	t.interUpdate = interpolatorUpdater(t, 3)
+	t.triggerUpdate = triggerUpdater(4)
+
+	t.AddTask(t.vidUpdate)
+	t.AddTask(t.stateUpdate)
+	t.AddTask(t.fpsUpdate)
+	t.AddTask(t.interUpdate)
+	t.AddTask(t.triggerUpdate)
+	if t.conf.source.path != "" {
+		t.AddTask(&reloadTask{BasicTask: NewBasicTask(2), tm: t})
+	}
+
+	t.sched = NewScheduler(t)
+	t.RegisterTrigger(t.sched)
+
+	var err error
+	if t.input, err = NewInputMap(t.conf); err != nil {
+		log.Println("Bad input settings:", err)
+		if t.input == nil {
+			t.input, _ = NewInputMap(DefaultConfig())
+		}
+	}
+	t.inputState = newInputState(t)
+	t.bus = NewEventBus()
+
+	if err := t.conf.Validate(); err != nil {
+		t.Shutdown(err)
+	} else if err := t.openRecordings(); err != nil {
+		t.Shutdown(err)
+	}
 	return t
 }
 
 var (
-	_This         TaskManager    = newTaskMgr()
-	vidUpdate     *SimpleTask    = &SimpleTask{NewBasicTask(1000), nil, func(w *sf.RenderWindow) { w.Display() }}
-	stateUpdate   *gameStateTask = &gameStateTask{BasicTask: NewBasicTask(500)}
-	fpsUpdate     *fpsTask       = &fpsTask{BasicTask: NewBasicTask(1), p: _This.GetSettings().Debug.PrintFPS}
-	timerUpdate   *timerTask     = &timerTask{BasicTask: NewBasicTask(2)}
-	interUpdate   *listTask      = interpolatorUpdater(3)
-	triggerUpdate *listTask      = triggerUpdater(4)
-
-//	inputUpdate   *inputTask     = &inputTask{NewBasicTask(5), nil}
+	_This     TaskManager
+	_ThisOnce sync.Once
 )
 
+// GetTaskManager returns the default engine, creating it from settings.ini
+// in the working directory the first time it is called. It panics if the
+// settings can't be loaded, use NewEngine to handle that yourself
+func GetTaskManager() TaskManager {
+	_ThisOnce.Do(func() {
+		var c Config
+		if err := loadSettings(&c); err != nil {
+			panic("grout: failed to load settings: " + err.Error())
+		}
+		_This = NewEngine(c)
+	})
+	return _This
+}
+
+// orDefault returns tm, or the default engine if tm is nil
+func orDefault(tm TaskManager) TaskManager {
+	if tm == nil {
+		return GetTaskManager()
+	}
+	return tm
+}
+
+// RegisterTrigger adds t to the default engine's triggers
 func RegisterTrigger(t Trigger) {
-	triggerUpdate.list = append(triggerUpdate.list, t)
+	GetTaskManager().RegisterTrigger(t)
 }
 
+// RegisterInterpolator adds i to the default engine's interpolators
 func RegisterInterpolator(i Interpolator) {
-	interUpdate.list = append(interUpdate.list, i)
+	GetTaskManager().RegisterInterpolator(i)
 }
 
-func init() {
-	_This.AddTask(vidUpdate)
-	_This.AddTask(stateUpdate)
-	_This.AddTask(fpsUpdate)
-	_This.AddTask(timerUpdate)
-	_This.AddTask(interUpdate)
-	//	_This.AddTask(inputUpdate)
+// InitialGameState sets the state the default engine starts in
+func InitialGameState(s CommandState) {
+	GetTaskManager().InitialGameState(s)
 }
 
-func InitialGameState(g GameState) {
-	stateUpdate.stk[0] = g
-	stateUpdate.stk[0].(GameState).Init(_This.getWindow())
+// StateScheduler returns the default engine's scheduler belonging to s
+func StateScheduler(s CommandState) *Scheduler {
+	return GetTaskManager().StateScheduler(s)
 }
 
-func GetTaskManager() TaskManager {
-	return _This
+func (tm *taskMgr) RegisterTrigger(t Trigger) {
+	tm.triggerUpdate.list = append(tm.triggerUpdate.list, t)
 }
 
-func (tm *taskMgr) getWindow() *sf.RenderWindow {
-	return tm.win
+func (tm *taskMgr) RegisterInterpolator(i Interpolator) {
+	tm.interUpdate.list = append(tm.interUpdate.list, i)
 }
 
-func (tm *taskMgr) GetEventQueue() *list.List {
-	t := tm.evQue
-	tm.queMutex.Lock()
-	tm.evQue = list.New()
-	tm.queMutex.Unlock()
-	return t
+// InitialGameState sets the state the game starts in. It is initialized on
+// the first update once the render target exists. A GameState is started
+// with FromGameState
+func (tm *taskMgr) InitialGameState(s CommandState) {
+	tm.stateUpdate.initial = s
+}
+
+func (tm *taskMgr) getTarget() RenderTarget {
+	return tm.target
 }
 
 func (tm *taskMgr) GetSettings() *Config {
 	return &tm.conf
 }
 
-func (tm *taskMgr) AddTask(t Task) bool {
-	if !t.Start() {
-		return false
+// AddTask starts t and adds it to the running tasks, if Start fails the
+// task isn't added and its error is returned
+func (tm *taskMgr) AddTask(t Task) error {
+	if err := t.Start(); err != nil {
+		return err
 	}
 
 	tm.taskList = append(tm.taskList, t)
 	sort.Sort(tm.taskList)
 
-	return true
+	return nil
 }
 
 func (tm *taskMgr) SuspendTask(t Task) {
@@ -176,44 +319,222 @@ func (tm *taskMgr) RemoveTask(t Task) {
 	}
 }
 
+// KillAllTasks ends the game, it is the same as Shutdown(nil)
 func (tm *taskMgr) KillAllTasks() {
+	tm.Shutdown(nil)
+}
+
+// Shutdown kills every task and cancels the engine's context. The first
+// non nil error given to Shutdown is what Execute returns
+func (tm *taskMgr) Shutdown(err error) {
+	tm.setErr(err)
+	tm.cancel()
 	for _, t := range tm.taskList {
 		t.SetCanKill(true)
 	}
 }
 
-func (tm *taskMgr) ElpsTime() time.Duration { return time.Since(tm.prev) }
+func (tm *taskMgr) setErr(err error) {
+	tm.errMutex.Lock()
+	defer tm.errMutex.Unlock()
+	if err != nil && tm.err == nil {
+		tm.err = err
+	}
+}
+
+func (tm *taskMgr) getErr() error {
+	tm.errMutex.Lock()
+	defer tm.errMutex.Unlock()
+	return tm.err
+}
+
+// Context returns a context which is cancelled when the engine shuts down,
+// goroutines started by tasks can use it to know when to exit
+func (tm *taskMgr) Context() context.Context { return tm.ctx }
+
+// ElpsTime returns the amount of time the current Update step covers. In
+// the fixed loop mode this is always one tick, otherwise it is the time
+// since the previous frame
+func (tm *taskMgr) ElpsTime() time.Duration { return tm.dt }
+
+// Alpha returns how far between the previous and the current update the
+// frame being drawn is, from 0 to 1. Drawing code can use it to blend
+// between the previous and current transforms. In the variable loop mode
+// it is always 1
+func (tm *taskMgr) Alpha() float32 { return tm.alpha }
+
+func (tm *taskMgr) Clock() Clock { return tm.clock }
+
+// Profiler returns the engine's profiler, it is enabled from the start if
+// the debug settings ask for profiling or a trace file
+func (tm *taskMgr) Profiler() *Profiler { return tm.prof }
+
+func (tm *taskMgr) isFixedStep() bool { return tm.conf.Loop.Mode == LoopFixed }
+
+// GameTime is the total of the time covered by every update step so far
+func (tm *taskMgr) GameTime() time.Duration { return tm.gameTime }
+
+// Ticks is the number of update steps run so far
+func (tm *taskMgr) Ticks() uint64 { return tm.tick }
+
+// Scheduler returns the engine's own scheduler, see Scheduler
+func (tm *taskMgr) Scheduler() *Scheduler { return tm.sched }
+
+// StateScheduler returns the scheduler belonging to the game state s, made
+// the first time it is asked for. Its timers stop while s is paused, carry
+// on when it is resumed and are cancelled once it leaves the stack, the way
+// SubscribeState subscriptions are
+func (tm *taskMgr) StateScheduler(s CommandState) *Scheduler { return tm.stateUpdate.scheduler(s) }
+
+// Input returns the action bindings from the [input] settings
+func (tm *taskMgr) Input() *InputMap { return tm.input }
+
+// InputState returns what input is held as of the current update step
+func (tm *taskMgr) InputState() *InputState { return tm.inputState }
+
+// Bus returns the engine's event bus, see EventBus
+func (tm *taskMgr) Bus() *EventBus { return tm.bus }
+
+func (tm *taskMgr) update(dt time.Duration) {
+	tm.tick++
+	tm.takeEvents()
+	tm.dt = dt
+	tm.gameTime += dt
+	tm.inputState.update(tm.events)
+	tm.bus.deliver(BeforeUpdate)
+	for _, t := range tm.taskList {
+		if !t.CanKill() {
+			s := tm.prof.begin()
+			t.Update()
+			tm.prof.end(t, PhaseUpdate, s)
+		}
+	}
+	tm.bus.deliver(AfterUpdate)
+}
+
+// advance runs the Update phase for a frame that took frame to produce,
+// either once with the whole frame time or as many fixed ticks as have
+// accumulated. At most MaxTicks ticks are run per frame, anything beyond
+// that is dropped so a slow frame can't snowball into slower ones
+func (tm *taskMgr) advance(frame time.Duration) {
+	if !tm.isFixedStep() {
+		tm.update(frame)
+		tm.alpha = 1
+		return
+	}
+
+	tm.acc += frame
+	if max := tm.step * time.Duration(tm.conf.Loop.MaxTicks); tm.acc > max {
+		tm.acc = max
+	}
+	for tm.acc >= tm.step {
+		tm.update(tm.step)
+		tm.acc -= tm.step
+	}
+	tm.alpha = float32(tm.acc) / float32(tm.step)
+}
+
+// Step runs a single frame: the update phase, every task's Draw and then
+// removal of the tasks that were killed. Execute calls it in a loop, tests
+// can call it directly to drive the engine one frame at a time. If there is
+// no render target yet a HeadlessTarget is used. It returns false once
+// there are no tasks left
+func (tm *taskMgr) Step() bool {
+	if tm.target == nil {
+		tm.target = NewHeadlessTarget(tm.w, tm.h)
+	}
+
+	fs := tm.prof.begin()
+	defer tm.prof.end(nil, PhaseFrame, fs)
+
+	if err := tm.parent.Err(); err != nil {
+		tm.Shutdown(err)
+	}
+
+	now := tm.clock.Now()
+	if tm.last.IsZero() {
+		tm.last = now
+	}
+	tm.advance(now.Sub(tm.last))
+	tm.last = now
+
+	for _, t := range tm.taskList {
+		if !t.CanKill() {
+			s := tm.prof.begin()
+			t.Draw(tm.target)
+			tm.prof.end(t, PhaseDraw, s)
+		}
+	}
+	tm.bus.deliver(EndOfFrame)
+	for i := 0; i < len(tm.taskList); i++ {
+		if tm.taskList[i].CanKill() {
+			if err := tm.taskList[i].Stop(); err != nil {
+				log.Println("Failed to stop task:", err)
+				tm.setErr(err)
+			}
+			tm.taskList.Remove(i)
+			i--
+		}
+	}
+	return len(tm.taskList) > 0
+}
+
+// shutdown runs once the last task is gone, writing out the profiler trace
+// if the debug settings ask for one
+func (tm *taskMgr) shutdown() {
+	log.Println("ENDING")
+	tm.cancel()
+	if tm.rec != nil {
+		if err := tm.rec.close(); err != nil {
+			log.Println("Failed to write recording:", err)
+		}
+	}
+	if f := tm.conf.Debug.TraceFile; f != "" {
+		if err := tm.prof.WriteTraceFile(f); err != nil {
+			log.Println("Failed to write trace:", err)
+		}
+	}
+}
+
+func (tm *taskMgr) executeHeadless() error {
+	if tm.target == nil {
+		tm.target = NewHeadlessTarget(tm.w, tm.h)
+	}
+	for tm.Step() {
+	}
+	tm.shutdown()
+	return tm.getErr()
+}
+
+// Execute runs the game until there are no tasks left. Unless the video
+// settings ask for headless mode or the engine was given a render target
+// it opens a window and feeds its events into the event queue. It returns
+// the first error the engine was shut down with, or that a task failed to
+// stop with
+func (tm *taskMgr) Execute() error {
+	if tm.conf.Video.Headless || tm.target != nil {
+		return tm.executeHeadless()
+	}
 
-func (tm *taskMgr) Execute() {
 	done := make(chan bool)
-	tm.evQue = list.New()
-	tm.win = sf.NewRenderWindow(sf.VideoMode{tm.w, tm.h, 32}, "Testing", sf.StyleDefault, sf.DefaultContextSettings())
+	tm.win = sf.NewRenderWindow(sf.VideoMode{tm.w, tm.h, 32}, tm.title, sf.StyleDefault, sf.DefaultContextSettings())
 	tm.win.SetActive(false)
+	tm.target = tm.win
+
+	// pads plugged in before the window opened don't send connect events
+	sf.JoystickUpdate()
+	for j := uint(0); j < sf.JoystickCount; j++ {
+		if sf.JoystickIsConnected(j) {
+			tm.PushEvent(sf.EventJoystickConnected{j})
+		}
+	}
 	go func(tm *taskMgr) {
 		runtime.LockOSThread()
 		defer tm.win.Close()
 		tm.win.SetFramerateLimit(tm.conf.Video.FPS)
-		for len(tm.taskList) > 0 {
-			tm.prev = timerUpdate.t
-			for _, t := range tm.taskList {
-				if !t.CanKill() {
-					t.Update()
-				}
-			}
-			for _, t := range tm.taskList {
-				if !t.CanKill() {
-					t.Draw(tm.win)
-				}
-			}
-			for i := 0; i < len(tm.taskList); i++ {
-				if tm.taskList[i].CanKill() {
-					tm.taskList[i].Stop()
-					tm.taskList.Remove(i)
-					i--
-				}
-			}
+		for tm.Step() {
 		}
-		log.Println("ENDING")
+		tm.shutdown()
 		done <- true
 	}(tm)
 
@@ -232,9 +553,7 @@ func (tm *taskMgr) Execute() {
 					v.Reset(sf.FloatRect{0, 0, float32(ev.Width), float32(ev.Height)})
 					tm.win.SetView(v)
 				default:
-					tm.queMutex.Lock()
-					tm.evQue.PushBack(event)
-					tm.queMutex.Unlock()
+					tm.PushEvent(event)
 				}
 			}
 		case <-done:
@@ -242,4 +561,5 @@ func (tm *taskMgr) Execute() {
 			tm.win.Close()
 		}
 	}
+	return tm.getErr()
 }
@@ -5,19 +5,23 @@
 package grout
 
 import (
-	sf "bitbucket.org/krepa098/gosfml2"
 	"container/list"
 	"log"
 	"time"
 )
 
+// Task is the unit of work the TaskManager runs every frame.
+//
+// Start is called when the task is added, if it returns an error the task
+// isn't added. Stop is called when the task is removed after being killed,
+// an error from it is reported by Execute
 type Task interface {
-	Start() bool
+	Start() error
 	OnSuspend()
 	Update()
-	Draw(*sf.RenderWindow)
+	Draw(RenderTarget)
 	OnResume()
-	Stop()
+	Stop() error
 
 	CanKill() bool
 	SetCanKill(k bool)
@@ -32,82 +36,76 @@ type BasicTask struct {
 func NewBasicTask(p int) BasicTask {
 	return BasicTask{false, p}
 }
-func (b *BasicTask) CanKill() bool         { return b.canKill }
-func (b *BasicTask) SetCanKill(k bool)     { b.canKill = k }
-func (b *BasicTask) GetPriority() int      { return b.priority }
-func (b *BasicTask) OnSuspend()            {}
-func (b *BasicTask) OnResume()             {}
-func (b *BasicTask) Draw(*sf.RenderWindow) {}
+func (b *BasicTask) CanKill() bool     { return b.canKill }
+func (b *BasicTask) SetCanKill(k bool) { b.canKill = k }
+func (b *BasicTask) GetPriority() int  { return b.priority }
+func (b *BasicTask) OnSuspend()        {}
+func (b *BasicTask) OnResume()         {}
+func (b *BasicTask) Draw(RenderTarget) {}
 
 type SimpleTask struct {
 	BasicTask
 	updateFunc func()
-	drawFunc   func(*sf.RenderWindow)
+	drawFunc   func(RenderTarget)
 }
 
-func (d *SimpleTask) Start() bool { return true }
-func (d *SimpleTask) Stop()       {}
+func (d *SimpleTask) Start() error { return nil }
+func (d *SimpleTask) Stop() error  { return nil }
 func (d *SimpleTask) Update() {
 	if d.updateFunc != nil {
 		d.updateFunc()
 	}
 }
-func (d *SimpleTask) Draw(w *sf.RenderWindow) {
+func (d *SimpleTask) Draw(w RenderTarget) {
 	if d.drawFunc != nil {
 		d.drawFunc(w)
 	}
 }
 
-func NewSimpleTask(p int, updateFunc func(), drawFunc func(*sf.RenderWindow)) *SimpleTask {
+func NewSimpleTask(p int, updateFunc func(), drawFunc func(RenderTarget)) *SimpleTask {
 	return &SimpleTask{NewBasicTask(p), updateFunc, drawFunc}
 }
 
 type fpsTask struct {
 	BasicTask
-	c int
-	t time.Time
-	p bool
+	c   int
+	t   time.Time
+	p   bool
+	clk Clock
 }
 
-func (f *fpsTask) Start() bool {
-	f.t = time.Now()
+func (f *fpsTask) Start() error {
+	f.t = f.clk.Now()
 	f.c = 0
-	return true
+	return nil
 }
-func (f *fpsTask) Stop() {}
-func (f *fpsTask) Update() {
+func (f *fpsTask) Stop() error { return nil }
+func (f *fpsTask) Update()     {}
+
+// Draw counts frames rather than updates, in the fixed loop mode there can
+// be several updates or none for each frame
+func (f *fpsTask) Draw(RenderTarget) {
 	f.c++
 
 	if f.p && (f.c%100 == 0) {
-		d := time.Since(f.t)
+		d := f.clk.Now().Sub(f.t)
 		log.Printf("FPS: %f\n", float64(f.c)/d.Seconds())
 	}
 }
 
-type timerTask struct {
-	BasicTask
-	t time.Time
-}
-
-func (t *timerTask) Stop()   {}
-func (t *timerTask) Update() { t.t = time.Now() }
-func (t *timerTask) Start() bool {
-	t.t = time.Now()
-	return true
-}
-
 type inputTask struct {
 	BasicTask
 	evQue *list.List
 }
 
-func (i *inputTask) Start() bool {
+func (i *inputTask) Start() error {
 	i.evQue = new(list.List)
-	return i.evQue != nil
+	return nil
 }
 
-func (i *inputTask) Stop() {
+func (i *inputTask) Stop() error {
 	i.evQue = nil
+	return nil
 }
 
 func (i *inputTask) Update() {
@@ -135,14 +133,20 @@ type ListItem interface {
 
 type listTask struct {
 	BasicTask
+	name string
 	list []ListItem
 	f    func(ListItem)
 }
 
-func (l *listTask) Stop() { l.list = nil }
-func (l *listTask) Start() bool {
+func (l *listTask) Name() string { return l.name }
+
+func (l *listTask) Stop() error {
+	l.list = nil
+	return nil
+}
+func (l *listTask) Start() error {
 	l.list = make([]ListItem, 0)
-	return true
+	return nil
 }
 func (l *listTask) Update() {
 	for j := 0; j < len(l.list); j++ {
@@ -64,6 +64,13 @@ type Map struct {
 	TSprites    []*sf.Sprite
 	drawTop     bool
 	TData       map[uint]map[string]string
+	tm          TaskManager
+}
+
+// SetTaskManager binds the map to an engine other than the default one, its
+// settings are used for loading the tile images and drawing
+func (m *Map) SetTaskManager(tm TaskManager) {
+	m.tm = tm
 }
 
 type ObjGroup struct {
@@ -96,7 +103,7 @@ func (m *Map) LoadImageData() (err error) {
 		numWide := uint(math.Floor(float64(ts.Image.Width) / float64(ts.TileWidth)))
 		numHigh := uint(math.Floor(float64(ts.Image.Height) / float64(ts.TileHeight)))
 		ts.LGid = numWide*numHigh + ts.FGid - 1
-		ts.Texture, err = sf.NewTextureFromFile(GetTaskManager().GetSettings().Paths.Res+"/"+ts.Image.Src, nil)
+		ts.Texture, err = sf.NewTextureFromFile(orDefault(m.tm).GetSettings().Paths.Res+"/"+ts.Image.Src, nil)
 		if err != nil {
 			return
 		}
@@ -190,7 +197,7 @@ func (m *Map) Draw(target sf.RenderTarget, renderStates sf.RenderStates) {
 			}
 		}
 	}
-	if GetTaskManager().GetSettings().Debug.ShowSprBound {
+	if orDefault(m.tm).GetSettings().Debug.ShowSprBound {
 		for _, o := range m.Collidables {
 			rs, _ := sf.NewRectangleShape()
 			rs.SetSize(sf.Vector2f{o.Width, o.Height})
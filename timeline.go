@@ -0,0 +1,365 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"math"
+)
+
+// Forever repeats a Timeline until it is killed
+const Forever = -1
+
+type timelineEntry struct {
+	at   float32
+	s    Seekable
+	fn   func()
+	last float32 // the time it was last put at, -1 until it is reached
+}
+
+func (e *timelineEntry) end() float32 {
+	if e.s == nil {
+		return e.at
+	}
+	return e.at + e.s.Duration()
+}
+
+// Timeline plays Seekables, like tweens, other interpolators and other
+// timelines, at set times. It is a Seekable itself so it is registered with
+// RegisterInterpolator and can be nested in other timelines:
+//
+//	tl := grout.NewSequence(
+//		grout.NewPositionTween(obj, 300, sf.Vector2f{100, 0}, grout.EaseOutQuad),
+//		grout.NewParallel(
+//			grout.NewAlphaTween(spr, 200, 0, nil),
+//			grout.NewScaleTween(obj, 200, sf.Vector2f{2, 2}, nil),
+//		),
+//	).Delay(500).Call(func() { log.Println("done") }).Repeat(2).Yoyo(true)
+//	tm.RegisterInterpolator(tl)
+//
+// What a timeline plays shouldn't be registered as well, the timeline sets
+// their time itself. When the timeline jumps, seeking or over a long frame,
+// everything it passes is finished in order first so nothing is skipped,
+// and going back puts what hasn't started yet back to its start.
+//
+// GetValue is how far through the current run the timeline is, from 0 to 1
+type Timeline struct {
+	timeBasedInterpolator
+	entries  []*timelineEntry
+	cursor   float32
+	lastAt   float32
+	repeat   int
+	yoyo     bool
+	scale    float32
+	cycle    int
+	started  bool
+	done     bool
+	onStart  func()
+	onUpdate func()
+	onDone   func()
+}
+
+// NewTimeline creates an empty timeline, add to it with Append, Join,
+// Insert, Delay and Call
+func NewTimeline() *Timeline {
+	return &Timeline{timeBasedInterpolator: timeBasedInterpolator{alive: true}, scale: 1}
+}
+
+// NewSequence creates a timeline playing each of items after the last
+func NewSequence(items ...Seekable) *Timeline {
+	t := NewTimeline()
+	for _, s := range items {
+		t.Append(s)
+	}
+	return t
+}
+
+// NewParallel creates a timeline playing all of items at once, it lasts as
+// long as the longest
+func NewParallel(items ...Seekable) *Timeline {
+	t := NewTimeline()
+	for _, s := range items {
+		t.Insert(0, s)
+	}
+	return t
+}
+
+func (t *Timeline) add(e *timelineEntry) {
+	i := len(t.entries)
+	for i > 0 && t.entries[i-1].at > e.at {
+		i--
+	}
+	t.entries = append(t.entries, nil)
+	copy(t.entries[i+1:], t.entries[i:])
+	t.entries[i] = e
+	if end := e.end(); end > t.cursor {
+		t.cursor = end
+	}
+	t.lastAt = e.at
+}
+
+// Append plays s after everything added so far, and any delay
+func (t *Timeline) Append(s Seekable) *Timeline {
+	t.add(&timelineEntry{t.cursor, s, nil, -1})
+	return t
+}
+
+// Join plays s at the same time as whatever was added last
+func (t *Timeline) Join(s Seekable) *Timeline {
+	t.add(&timelineEntry{t.lastAt, s, nil, -1})
+	return t
+}
+
+// Insert plays s at ms milliseconds into the timeline
+func (t *Timeline) Insert(ms float32, s Seekable) *Timeline {
+	t.add(&timelineEntry{ms, s, nil, -1})
+	return t
+}
+
+// Delay leaves a gap of ms milliseconds before whatever is appended next,
+// at the end of the timeline it makes it last that much longer
+func (t *Timeline) Delay(ms float32) *Timeline {
+	t.cursor += ms
+	t.lastAt = t.cursor
+	return t
+}
+
+// Call calls fn when the timeline reaches this point, after everything
+// added so far. It is called again on every forwards run when the timeline
+// repeats, and each time it comes back round to it from before it, though
+// not when a yoyo passes it going backwards
+func (t *Timeline) Call(fn func()) *Timeline {
+	t.add(&timelineEntry{t.cursor, nil, fn, -1})
+	return t
+}
+
+// Repeat plays the timeline n more times after the first, or until it is
+// killed if n is Forever
+func (t *Timeline) Repeat(n int) *Timeline {
+	t.repeat = n
+	return t
+}
+
+// Yoyo makes every other repeat play backwards, so the timeline goes back
+// and forth. It needs Repeat to do anything
+func (t *Timeline) Yoyo(yoyo bool) *Timeline {
+	t.yoyo = yoyo
+	return t
+}
+
+// SetTimeScale speeds the timeline up, or slows it down, by s. 2 plays it
+// twice as fast and 0.5 at half speed
+func (t *Timeline) SetTimeScale(s float32) *Timeline {
+	t.scale = s
+	return t
+}
+
+// OnStart calls fn the first time the timeline is updated
+func (t *Timeline) OnStart(fn func()) *Timeline {
+	t.onStart = fn
+	return t
+}
+
+// OnUpdate calls fn every time the timeline is updated, after it has set
+// everything it plays
+func (t *Timeline) OnUpdate(fn func()) *Timeline {
+	t.onUpdate = fn
+	return t
+}
+
+// OnComplete calls fn when the timeline finishes its last repeat, which
+// never happens when it repeats Forever
+func (t *Timeline) OnComplete(fn func()) *Timeline {
+	t.onDone = fn
+	return t
+}
+
+// length is how long a single run of the timeline lasts
+func (t *Timeline) length() float32 {
+	return t.cursor
+}
+
+// total is how long every run of the timeline lasts together, in its own
+// unscaled time
+func (t *Timeline) total() float32 {
+	if t.repeat == Forever {
+		return float32(math.Inf(1))
+	}
+	return t.length() * float32(t.repeat+1)
+}
+
+// Duration is how long the timeline lasts with every repeat at its time
+// scale
+func (t *Timeline) Duration() float32 {
+	if t.scale <= 0 {
+		return float32(math.Inf(1))
+	}
+	return t.total() / t.scale
+}
+
+// Elapsed is how far into the timeline it is, at its time scale. One that
+// repeats Forever goes back round to 0 every two runs
+func (t *Timeline) Elapsed() float32 {
+	if t.scale <= 0 {
+		return 0
+	}
+	return t.elpTime / t.scale
+}
+
+func (t *Timeline) Update(dT float32) {
+	t.jump(t.elpTime + dT*t.scale)
+}
+
+// SetTime seeks to ms into the timeline, at its time scale
+func (t *Timeline) SetTime(ms float32) {
+	t.jump(ms * t.scale)
+}
+
+// reversed reports whether run n of the timeline plays backwards
+func (t *Timeline) reversed(n int) bool {
+	return t.yoyo && n%2 == 1
+}
+
+// jump moves the timeline to elp in its own time
+func (t *Timeline) jump(elp float32) {
+	if !t.started {
+		t.started = true
+		if t.onStart != nil {
+			t.onStart()
+		}
+	}
+
+	length, total := t.length(), t.total()
+	if t.repeat == Forever && length > 0 {
+		elp = t.wrap(elp)
+	}
+	finished := elp >= total
+	elp = clamp(0, total, elp)
+
+	cycle, local := 0, length
+	switch {
+	case finished:
+		// the end of the last run rather than the start of another
+		cycle = t.repeat
+	case length > 0:
+		cycle = int(elp / length)
+		local = clamp(0, length, elp-float32(cycle)*length)
+	}
+
+	// finish each run that is passed going forwards, starting the next
+	// forwards one from scratch so its calls happen again
+	for t.cycle < cycle {
+		if t.reversed(t.cycle) {
+			t.setLocal(0)
+		} else {
+			t.setLocal(length)
+		}
+		t.cycle++
+		if !t.reversed(t.cycle) {
+			t.rewind()
+		}
+	}
+	// going back only needs the run that was playing taken back to the
+	// right end, setLocal puts back whatever is after the new time
+	if cycle < t.cycle {
+		if t.reversed(t.cycle) {
+			t.setLocal(length)
+		} else {
+			t.setLocal(0)
+		}
+		t.cycle = cycle
+	}
+	if t.reversed(cycle) {
+		local = length - local
+	}
+	t.setLocal(local)
+
+	if t.repeat == Forever && t.cycle >= 2 {
+		n := t.cycle / 2
+		t.cycle -= 2 * n
+		elp -= float32(2*n) * length
+	}
+	t.elpTime = elp
+	t.val = 1
+	if length > 0 {
+		t.val = local / length
+	}
+	if t.onUpdate != nil {
+		t.onUpdate()
+	}
+
+	switch {
+	case finished && !t.done:
+		t.done = true
+		t.Kill()
+		if t.onDone != nil {
+			t.onDone()
+		}
+	case !finished:
+		t.done, t.alive = false, true
+	}
+}
+
+// wrap keeps the time of a timeline repeating Forever within two runs of
+// where it is, so it doesn't grow until it loses precision. Two runs keeps
+// a yoyo going the same way, and jump then takes the time back below two
+// runs. A jump over many runs at once only plays through the last few
+func (t *Timeline) wrap(elp float32) float32 {
+	if elp <= t.elpTime {
+		return elp
+	}
+	pair := 2 * t.length()
+	if k := float32(math.Floor(float64((elp - t.elpTime) / pair))); k > 1 {
+		elp -= (k - 1) * pair
+	}
+	return elp
+}
+
+// rewind puts everything the timeline plays back to its start for another
+// forwards run, latest first so the earliest wins
+func (t *Timeline) rewind() {
+	for i := len(t.entries) - 1; i >= 0; i-- {
+		e := t.entries[i]
+		e.last = -1
+		if e.s != nil {
+			e.s.SetTime(0)
+		}
+	}
+}
+
+// setLocal puts everything the timeline plays where it should be ms into a
+// single forwards run
+func (t *Timeline) setLocal(ms float32) {
+	// entries not reached yet go back to their start, latest first so the
+	// earliest wins when several set the same thing
+	for i := len(t.entries) - 1; i >= 0; i-- {
+		if e := t.entries[i]; e.at > ms && e.last >= 0 {
+			if e.s != nil {
+				e.s.SetTime(0)
+			}
+			e.last = -1
+		}
+	}
+
+	for _, e := range t.entries {
+		if e.at > ms {
+			break
+		}
+		local := ms - e.at
+		if e.s == nil {
+			local = 0
+		} else if d := e.s.Duration(); local > d {
+			local = d
+		}
+		if local == e.last {
+			continue
+		}
+		e.last = local
+		if e.s != nil {
+			e.s.SetTime(local)
+		} else {
+			e.fn()
+		}
+	}
+}
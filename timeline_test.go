@@ -0,0 +1,124 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTimelineCallRepeat(t *testing.T) {
+	var calls []string
+	tl := NewTimeline().
+		Call(func() { calls = append(calls, "start") }).
+		Append(NewFloatTween(100, 0, 1, nil, func(float32) {})).
+		Call(func() { calls = append(calls, "end") }).
+		Repeat(2)
+
+	for i := 0; i < 4; i++ {
+		tl.Update(100)
+	}
+	want := []string{"start", "end", "start", "end", "start", "end"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+	if tl.IsAlive() {
+		t.Error("still alive after its last repeat")
+	}
+
+	// passing several runs in one update still calls each of them
+	calls = nil
+	tl = NewTimeline().
+		Append(NewFloatTween(100, 0, 1, nil, func(float32) {})).
+		Call(func() { calls = append(calls, "end") }).
+		Repeat(2)
+	tl.Update(1000)
+	if len(calls) != 3 {
+		t.Errorf("called %d times in one long update, want 3", len(calls))
+	}
+}
+
+func TestTimelineYoyo(t *testing.T) {
+	var v float32
+	var calls int
+	tl := NewSequence(NewFloatTween(100, 0, 10, nil, func(x float32) { v = x })).
+		Call(func() { calls++ }).
+		Repeat(2).Yoyo(true)
+
+	steps := []struct {
+		dT, want float32
+	}{
+		{50, 5},
+		{50, 10},
+		{25, 7.5}, // second run goes back
+		{75, 0},
+		{50, 5}, // and the third forwards again
+		{100, 10},
+	}
+	for i, s := range steps {
+		tl.Update(s.dT)
+		if abs32(v-s.want) > 1e-4 {
+			t.Errorf("step %d: value %v, want %v", i, v, s.want)
+		}
+	}
+	// the end is reached going forwards on the first and third runs only
+	if calls != 2 {
+		t.Errorf("called %d times, want 2", calls)
+	}
+	if tl.IsAlive() {
+		t.Error("still alive after its last repeat")
+	}
+}
+
+func TestTimelineSeekBack(t *testing.T) {
+	var a, b float32
+	var calls int
+	tl := NewSequence(
+		NewFloatTween(100, 0, 1, nil, func(x float32) { a = x }),
+		NewFloatTween(100, 0, 1, nil, func(x float32) { b = x }),
+	).Call(func() { calls++ })
+
+	tl.SetTime(200)
+	if a != 1 || b != 1 || calls != 1 {
+		t.Fatalf("at the end a=%v b=%v calls=%d", a, b, calls)
+	}
+	tl.SetTime(50)
+	if abs32(a-0.5) > 1e-4 || b != 0 {
+		t.Errorf("seeking back a=%v b=%v, want 0.5 and 0", a, b)
+	}
+	if !tl.IsAlive() {
+		t.Error("not alive again after seeking back")
+	}
+	tl.SetTime(200)
+	if calls != 2 {
+		t.Errorf("called %d times, want 2 after coming back round", calls)
+	}
+}
+
+func TestTimelineForeverWraps(t *testing.T) {
+	var v float32
+	var calls int
+	tl := NewSequence(NewFloatTween(100, 0, 10, nil, func(x float32) { v = x })).
+		Call(func() { calls++ }).
+		Repeat(Forever).Yoyo(true)
+
+	for i := 0; i < 1000; i++ {
+		tl.Update(30)
+	}
+	// 30000ms is 150 pairs of runs, so the value is back at the start
+	if tl.elpTime < 0 || tl.elpTime >= 200 {
+		t.Errorf("elapsed %v, want it within two runs", tl.elpTime)
+	}
+	if abs32(v) > 1e-3 {
+		t.Errorf("value %v, want 0", v)
+	}
+	if calls != 150 {
+		t.Errorf("called %d times, want 150", calls)
+	}
+	tl.Update(50)
+	if abs32(v-5) > 1e-3 {
+		t.Errorf("value %v, want 5", v)
+	}
+}
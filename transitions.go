@@ -0,0 +1,249 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// Transition draws the change from one game state to the next. While it
+// runs neither state is updated or gets input, but both can be drawn.
+//
+// Duration is how long the transition lasts in milliseconds, the same as
+// the time based interpolators. Draw is called once per frame with the
+// progress p going from 0 to 1, from and to draw the outgoing and incoming
+// states into whatever target they are given. Popping the last state
+// transitions to a black screen
+type Transition interface {
+	Duration() float32
+	Draw(w RenderTarget, from, to func(RenderTarget), p float32)
+}
+
+type transitioned struct {
+	GameState
+	t Transition
+}
+
+// WithTransition wraps the state returned from the original two value
+// Update so that the change plays t. Pass a nil state to pop with a
+// transition:
+//
+//	return grout.WithTransition(next, grout.NewFadeTransition(500, sf.ColorBlack())), false
+//	return grout.WithTransition(nil, grout.NewSlideTransition(300, grout.DirRight)), true
+//
+// A CommandState uses StateCommand.With instead
+func WithTransition(next GameState, t Transition) GameState {
+	return &transitioned{next, t}
+}
+
+type Direction int
+
+const (
+	DirLeft Direction = iota
+	DirRight
+	DirUp
+	DirDown
+)
+
+func (d Direction) vector() sf.Vector2f {
+	switch d {
+	case DirLeft:
+		return sf.Vector2f{-1, 0}
+	case DirRight:
+		return sf.Vector2f{1, 0}
+	case DirUp:
+		return sf.Vector2f{0, -1}
+	default:
+		return sf.Vector2f{0, 1}
+	}
+}
+
+func withAlpha(c sf.Color, a float32) sf.Color {
+	c.A = uint8(clamp(0, 255, a*float32(c.A)))
+	return c
+}
+
+// drawOverlay covers the whole target with c, ignoring the current view
+func drawOverlay(w RenderTarget, c sf.Color) {
+	v := w.GetView()
+	w.SetView(w.GetDefaultView())
+	sz := w.GetSize()
+	rs, _ := sf.NewRectangleShape()
+	rs.SetSize(sf.Vector2f{float32(sz.X), float32(sz.Y)})
+	rs.SetFillColor(c)
+	w.Draw(rs, sf.DefaultRenderStates())
+	w.SetView(v)
+}
+
+// offscreen renders a state into a texture so transitions can move, crop
+// and blend it. Targets without render texture support, like the
+// HeadlessTarget, get ok == false and the transitions fall back to cutting
+// between the states half way through
+type offscreen struct {
+	rt  *sf.RenderTexture
+	spr *sf.Sprite
+}
+
+func (o *offscreen) render(w RenderTarget, draw func(RenderTarget)) (spr *sf.Sprite, ok bool) {
+	if _, headless := w.(*HeadlessTarget); headless {
+		return nil, false
+	}
+	sz := w.GetSize()
+	if o.rt == nil || o.rt.GetSize() != sz {
+		rt, err := sf.NewRenderTexture(sz.X, sz.Y, false)
+		if err != nil {
+			return nil, false
+		}
+		o.rt = rt
+		if o.spr, err = sf.NewSprite(rt.GetTexture()); err != nil {
+			o.rt = nil
+			return nil, false
+		}
+	}
+	o.rt.SetView(o.rt.GetDefaultView())
+	draw(o.rt)
+	o.rt.Display()
+	o.spr.SetTextureRect(sf.IntRect{0, 0, int(sz.X), int(sz.Y)})
+	o.spr.SetPosition(sf.Vector2f{0, 0})
+	o.spr.SetColor(sf.ColorWhite())
+	return o.spr, true
+}
+
+// drawSprites draws the offscreen sprites ignoring the current view
+func drawSprites(w RenderTarget, sprs ...*sf.Sprite) {
+	v := w.GetView()
+	w.SetView(w.GetDefaultView())
+	for _, s := range sprs {
+		w.Draw(s, sf.DefaultRenderStates())
+	}
+	w.SetView(v)
+}
+
+func cut(w RenderTarget, from, to func(RenderTarget), p float32) {
+	if p < 0.5 {
+		from(w)
+	} else {
+		to(w)
+	}
+}
+
+// FadeTransition fades the outgoing state out to a colour for the first
+// half of the duration and the incoming state in from it for the second
+type FadeTransition struct {
+	ms    float32
+	Color sf.Color
+}
+
+func NewFadeTransition(ms float32, c sf.Color) *FadeTransition {
+	return &FadeTransition{ms, c}
+}
+
+func (f *FadeTransition) Duration() float32 { return f.ms }
+func (f *FadeTransition) Draw(w RenderTarget, from, to func(RenderTarget), p float32) {
+	cut(w, from, to, p)
+	if p < 0.5 {
+		drawOverlay(w, withAlpha(f.Color, p*2))
+	} else {
+		drawOverlay(w, withAlpha(f.Color, (1-p)*2))
+	}
+}
+
+// CrossfadeTransition blends the incoming state in over the outgoing one
+type CrossfadeTransition struct {
+	ms       float32
+	from, to offscreen
+}
+
+func NewCrossfadeTransition(ms float32) *CrossfadeTransition {
+	return &CrossfadeTransition{ms: ms}
+}
+
+func (c *CrossfadeTransition) Duration() float32 { return c.ms }
+func (c *CrossfadeTransition) Draw(w RenderTarget, from, to func(RenderTarget), p float32) {
+	fs, ok := c.from.render(w, from)
+	if !ok {
+		cut(w, from, to, p)
+		return
+	}
+	ts, ok := c.to.render(w, to)
+	if !ok {
+		cut(w, from, to, p)
+		return
+	}
+	ts.SetColor(withAlpha(sf.ColorWhite(), p))
+	drawSprites(w, fs, ts)
+}
+
+// SlideTransition pushes the outgoing state off the screen in the given
+// direction while the incoming state follows it on
+type SlideTransition struct {
+	ms       float32
+	dir      Direction
+	from, to offscreen
+}
+
+func NewSlideTransition(ms float32, dir Direction) *SlideTransition {
+	return &SlideTransition{ms: ms, dir: dir}
+}
+
+func (s *SlideTransition) Duration() float32 { return s.ms }
+func (s *SlideTransition) Draw(w RenderTarget, from, to func(RenderTarget), p float32) {
+	fs, ok := s.from.render(w, from)
+	if !ok {
+		cut(w, from, to, p)
+		return
+	}
+	ts, ok := s.to.render(w, to)
+	if !ok {
+		cut(w, from, to, p)
+		return
+	}
+
+	sz := w.GetSize()
+	d := s.dir.vector()
+	off := sf.Vector2f{d.X * float32(sz.X), d.Y * float32(sz.Y)}
+	fs.SetPosition(off.TimesScalar(p))
+	ts.SetPosition(off.TimesScalar(p - 1))
+	drawSprites(w, fs, ts)
+}
+
+// WipeTransition reveals the incoming state over the outgoing one with an
+// edge moving across the screen in the given direction
+type WipeTransition struct {
+	ms  float32
+	dir Direction
+	to  offscreen
+}
+
+func NewWipeTransition(ms float32, dir Direction) *WipeTransition {
+	return &WipeTransition{ms: ms, dir: dir}
+}
+
+func (wp *WipeTransition) Duration() float32 { return wp.ms }
+func (wp *WipeTransition) Draw(w RenderTarget, from, to func(RenderTarget), p float32) {
+	ts, ok := wp.to.render(w, to)
+	if !ok {
+		cut(w, from, to, p)
+		return
+	}
+	from(w)
+
+	sz := w.GetSize()
+	sw, sh := int(float32(sz.X)*p), int(float32(sz.Y)*p)
+	var r sf.IntRect
+	switch wp.dir {
+	case DirRight:
+		r = sf.IntRect{0, 0, sw, int(sz.Y)}
+	case DirLeft:
+		r = sf.IntRect{int(sz.X) - sw, 0, sw, int(sz.Y)}
+	case DirDown:
+		r = sf.IntRect{0, 0, int(sz.X), sh}
+	default:
+		r = sf.IntRect{0, int(sz.Y) - sh, int(sz.X), sh}
+	}
+	ts.SetTextureRect(r)
+	ts.SetPosition(sf.Vector2f{float32(r.Left), float32(r.Top)})
+	drawSprites(w, ts)
+}
@@ -5,7 +5,7 @@
 package grout
 
 func triggerUpdater(pri int) *listTask {
-	return &listTask{BasicTask: NewBasicTask(pri), f: func(l ListItem) {
+	return &listTask{BasicTask: NewBasicTask(pri), name: "triggers", f: func(l ListItem) {
 		it := l.(Trigger)
 		it.Tick()
 	}}
@@ -24,6 +24,12 @@ type BaseTrigger struct {
 	alive     bool
 }
 
+// NewTrigger creates a trigger which calls handler whenever cond returns
+// true when it is ticked. If once is set it is killed after firing
+func NewTrigger(cond func() bool, handler func(), once bool) *BaseTrigger {
+	return &BaseTrigger{cond, handler, once, true}
+}
+
 func (b *BaseTrigger) Kill()         { b.alive = false }
 func (b *BaseTrigger) IsAlive() bool { return b.alive }
 func (b *BaseTrigger) Tick() {
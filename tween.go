@@ -0,0 +1,166 @@
+// Copyright (C) 2014 zeroshade. All rights reserved
+// Use of this source code is goverened by the GPLv2 license
+// which can be found in the license.txt file
+
+package grout
+
+import (
+	sf "bitbucket.org/krepa098/gosfml2"
+)
+
+// Transformable is anything with a position, scale, rotation and origin
+// like GameObject, sf.Sprite, sf.Text or the SFML shapes
+type Transformable interface {
+	GetPosition() sf.Vector2f
+	SetPosition(sf.Vector2f)
+	GetScale() sf.Vector2f
+	SetScale(sf.Vector2f)
+	GetRotation() float32
+	SetRotation(float32)
+	GetOrigin() sf.Vector2f
+	SetOrigin(sf.Vector2f)
+}
+
+// Colorable is anything drawn with a colour, like GameObject, SpriteObj,
+// sf.Sprite or sf.Text. The
+// shapes have a fill and an outline colour instead, tween those with
+// NewColorTween and a setter
+type Colorable interface {
+	GetColor() sf.Color
+	SetColor(sf.Color)
+}
+
+// Tween is an Interpolator that sets what it animates directly, rather than
+// leaving the value to be copied out of GetValue every frame. Register it
+// with RegisterInterpolator like any other:
+//
+//	grout.RegisterInterpolator(grout.NewPositionTween(obj, 400, sf.Vector2f{200, 100}, grout.EaseOutCubic))
+//
+// The tweens bound to a target start from wherever the target is on their
+// first update, so they carry on from anything that moved it before.
+// GetValue is the eased progress, going from 0 to 1 unless the easing
+// overshoots
+type Tween struct {
+	timeBasedInterpolator
+	ease    Easing
+	begin   func()
+	apply   func(p float32)
+	started bool
+}
+
+// NewTween calls apply every update with the eased progress from 0 to 1
+// over timespan milliseconds, it is what the other tweens are built on
+func NewTween(timespan float32, ease Easing, apply func(p float32)) *Tween {
+	if ease == nil {
+		ease = Linear
+	}
+	return &Tween{timeBasedInterpolator{false, 0, timespan, true, 0}, ease, nil, apply, false}
+}
+
+func (t *Tween) SetTime(ms float32) { t.seek(ms, t.Update) }
+
+func (t *Tween) Update(dT float32) {
+	if !t.started {
+		t.started = true
+		if t.begin != nil {
+			t.begin()
+		}
+	}
+	t.elpTime += dT
+
+	b := float32(1)
+	if t.totTime > 0 {
+		b = clamp(0, 1, t.elpTime/t.totTime)
+	}
+	t.val = t.ease(b)
+	t.apply(t.val)
+
+	if t.elpTime > t.totTime {
+		t.Kill()
+	}
+}
+
+func lerp(a, b, p float32) float32 {
+	return a + (b-a)*p
+}
+
+func lerpVector(a, b sf.Vector2f, p float32) sf.Vector2f {
+	return sf.Vector2f{lerp(a.X, b.X, p), lerp(a.Y, b.Y, p)}
+}
+
+func lerpColor(a, b sf.Color, p float32) sf.Color {
+	ch := func(x, y uint8) uint8 {
+		return uint8(clamp(0, 255, lerp(float32(x), float32(y), p)+0.5))
+	}
+	return sf.Color{ch(a.R, b.R), ch(a.G, b.G), ch(a.B, b.B), ch(a.A, b.A)}
+}
+
+// NewFloatTween calls set with a value going from start to end
+func NewFloatTween(timespan, start, end float32, ease Easing, set func(float32)) *Tween {
+	return NewTween(timespan, ease, func(p float32) { set(lerp(start, end, p)) })
+}
+
+// NewVectorTween calls set with a vector going from start to end
+func NewVectorTween(timespan float32, start, end sf.Vector2f, ease Easing, set func(sf.Vector2f)) *Tween {
+	return NewTween(timespan, ease, func(p float32) { set(lerpVector(start, end, p)) })
+}
+
+// NewColorTween calls set with a colour going from start to end, blending
+// each channel including alpha
+func NewColorTween(timespan float32, start, end sf.Color, ease Easing, set func(sf.Color)) *Tween {
+	return NewTween(timespan, ease, func(p float32) { set(lerpColor(start, end, p)) })
+}
+
+// vectorProperty tweens a vector property of a target from its value on
+// the first update
+func vectorProperty(timespan float32, end sf.Vector2f, ease Easing, get func() sf.Vector2f, set func(sf.Vector2f)) *Tween {
+	var start sf.Vector2f
+	tw := NewTween(timespan, ease, func(p float32) { set(lerpVector(start, end, p)) })
+	tw.begin = func() { start = get() }
+	return tw
+}
+
+// NewPositionTween moves t to end
+func NewPositionTween(t Transformable, timespan float32, end sf.Vector2f, ease Easing) *Tween {
+	return vectorProperty(timespan, end, ease, t.GetPosition, t.SetPosition)
+}
+
+// NewScaleTween scales t to end
+func NewScaleTween(t Transformable, timespan float32, end sf.Vector2f, ease Easing) *Tween {
+	return vectorProperty(timespan, end, ease, t.GetScale, t.SetScale)
+}
+
+// NewOriginTween moves the origin of t to end
+func NewOriginTween(t Transformable, timespan float32, end sf.Vector2f, ease Easing) *Tween {
+	return vectorProperty(timespan, end, ease, t.GetOrigin, t.SetOrigin)
+}
+
+// NewRotationTween turns t to end degrees. It goes the way the numbers do,
+// so 350 to 10 turns back 340 degrees rather than on by 20
+func NewRotationTween(t Transformable, timespan, end float32, ease Easing) *Tween {
+	var start float32
+	tw := NewTween(timespan, ease, func(p float32) { t.SetRotation(lerp(start, end, p)) })
+	tw.begin = func() { start = t.GetRotation() }
+	return tw
+}
+
+// NewTintTween changes the colour of c to end
+func NewTintTween(c Colorable, timespan float32, end sf.Color, ease Easing) *Tween {
+	var start sf.Color
+	tw := NewTween(timespan, ease, func(p float32) { c.SetColor(lerpColor(start, end, p)) })
+	tw.begin = func() { start = c.GetColor() }
+	return tw
+}
+
+// NewAlphaTween fades c to the alpha end, leaving the rest of its colour
+// alone, so 0 fades it out and 255 back in
+func NewAlphaTween(c Colorable, timespan float32, end uint8, ease Easing) *Tween {
+	var start uint8
+	tw := NewTween(timespan, ease, func(p float32) {
+		col := c.GetColor()
+		col.A = uint8(clamp(0, 255, lerp(float32(start), float32(end), p)+0.5))
+		c.SetColor(col)
+	})
+	tw.begin = func() { start = c.GetColor().A }
+	return tw
+}